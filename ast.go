@@ -0,0 +1,252 @@
+package rjson
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// SegmentKind is the type of a single step in a path
+type SegmentKind int
+
+const (
+	KeySegment      SegmentKind = iota // Object key, e.g a or ["a"]
+	IndexSegment                       // Array index, negative indexes count from the end, e.g [0] or [-1]
+	LastSegment                        // Last array element, [-]
+	IteratorSegment                    // Applies the rest of the path to every array element, []
+	SliceSegment                       // Applies the rest of the path to a part of the array, e.g [1:5:2]
+	FilterSegment                      // Selects the array elements matching a predicate, e.g [?(@.type == "video")]
+	WildcardSegment                    // Applies the rest of the path to every object value, * or [*]
+	DescentSegment                     // Collects the values of a key at any depth, e.g ..key
+	PointerSegment                     // RFC 6901 json pointer reference token, resolved as a key or index depending on the document
+	RootSegment                        // Root of the document, $
+	CurrentSegment                     // Current element, @
+)
+
+// Segment is a single step in a path, only the fields used by its kind are set
+type Segment struct {
+	Kind    SegmentKind
+	Key     string      // KeySegment, DescentSegment and PointerSegment
+	Index   int         // IndexSegment
+	Slice   SliceRange  // SliceSegment
+	Filter  *FilterExpr // FilterSegment
+	Aligned bool        // IteratorSegment, SliceSegment and WildcardSegment, elements the rest of the path doesnt resolve for become null instead of being left out
+}
+
+// SliceRange holds the bounds of a slice, nil bounds are left open
+type SliceRange struct {
+	Start *int
+	End   *int
+	Step  *int
+}
+
+// FilterOp is the operation of a filter expression
+type FilterOp int
+
+const (
+	FilterExists FilterOp = iota // The path of the operand resolves, e.g @.hdr
+	FilterAnd
+	FilterOr
+	FilterNot
+	FilterEq
+	FilterNe
+	FilterLt
+	FilterLe
+	FilterGt
+	FilterGe
+)
+
+var filterOpSymbols = map[FilterOp]string{
+	FilterAnd: "&&",
+	FilterOr:  "||",
+	FilterEq:  "==",
+	FilterNe:  "!=",
+	FilterLt:  "<",
+	FilterLe:  "<=",
+	FilterGt:  ">",
+	FilterGe:  ">=",
+}
+
+// FilterExpr is a predicate selecting array elements, e.g @.type == "video"
+type FilterExpr struct {
+	Op       FilterOp
+	Children []FilterExpr    // Sub expressions of &&, || and !
+	Operands []FilterOperand // Compared values, or the checked path for existence checks
+}
+
+// FilterOperand is either a path relative to the current element (@), a path relative to the root ($) or a literal value
+type FilterOperand struct {
+	Current  bool
+	Root     bool
+	Segments []Segment
+	Literal  any // Stored the same way json.Unmarshal decodes into an any, so numbers are float64
+}
+
+// Function is a builtin function the value is piped into, e.g | join(", ")
+type Function struct {
+	Name string
+	Args []string
+}
+
+// Alternative is one of the paths separated by |, later ones are only tried when the earlier ones dont resolve
+type Alternative struct {
+	Segments []Segment
+}
+
+// writeSegment writes the segment in its canonical form, first tells if nothing comes before it
+func writeSegment(b *strings.Builder, seg Segment, first bool) {
+	switch seg.Kind {
+	case KeySegment, PointerSegment:
+		if !first {
+			b.WriteRune(Divider)
+		}
+		b.WriteString(quoteKey(seg.Key))
+	case IndexSegment:
+		b.WriteRune(ArrayOpen)
+		b.WriteString(strconv.Itoa(seg.Index))
+		b.WriteRune(ArrayClose)
+	case LastSegment:
+		b.WriteRune(ArrayOpen)
+		b.WriteRune(ArrayLast)
+		b.WriteRune(ArrayClose)
+	case IteratorSegment:
+		b.WriteRune(ArrayOpen)
+		b.WriteRune(ArrayClose)
+		writeAligned(b, seg)
+	case SliceSegment:
+		b.WriteRune(ArrayOpen)
+		for i, bound := range []*int{seg.Slice.Start, seg.Slice.End, seg.Slice.Step} {
+			if i > 0 && (i < 2 || bound != nil) {
+				b.WriteRune(SliceSeparator)
+			}
+			if bound != nil {
+				b.WriteString(strconv.Itoa(*bound))
+			}
+		}
+		b.WriteRune(ArrayClose)
+		writeAligned(b, seg)
+	case FilterSegment:
+		b.WriteRune(ArrayOpen)
+		b.WriteRune(FilterStart)
+		b.WriteRune('(')
+		if seg.Filter != nil {
+			writeFilter(b, *seg.Filter)
+		}
+		b.WriteRune(')')
+		b.WriteRune(ArrayClose)
+	case WildcardSegment:
+		if !first {
+			b.WriteRune(Divider)
+		}
+		b.WriteRune(Wildcard)
+		writeAligned(b, seg)
+	case DescentSegment:
+		b.WriteRune(Divider)
+		b.WriteRune(Divider)
+		b.WriteString(quoteKey(seg.Key))
+	case RootSegment:
+		b.WriteRune(RootElement)
+	case CurrentSegment:
+		b.WriteRune(CurrentElement)
+	}
+}
+
+func writeAligned(b *strings.Builder, seg Segment) {
+	if seg.Aligned {
+		b.WriteRune(AlignMarker)
+	}
+}
+
+func writeSegments(b *strings.Builder, segments []Segment) {
+	for i, seg := range segments {
+		writeSegment(b, seg, i == 0)
+	}
+}
+
+// writeFilter writes the expression, sub expressions are always wrapped in parentheses so grouping survives reparsing
+func writeFilter(b *strings.Builder, expr FilterExpr) {
+	// Expressions made by hand that are missing parts are left out
+	if children, operands := filterArity(expr.Op); len(expr.Children) < children || len(expr.Operands) < operands {
+		return
+	}
+
+	switch expr.Op {
+	case FilterAnd, FilterOr:
+		for i, child := range expr.Children {
+			if i > 0 {
+				b.WriteString(" " + filterOpSymbols[expr.Op] + " ")
+			}
+			writeFilterChild(b, child)
+		}
+	case FilterNot:
+		b.WriteRune('!')
+		writeFilterChild(b, expr.Children[0])
+	case FilterExists:
+		writeOperand(b, expr.Operands[0])
+	default:
+		writeOperand(b, expr.Operands[0])
+		b.WriteString(" " + filterOpSymbols[expr.Op] + " ")
+		writeOperand(b, expr.Operands[1])
+	}
+}
+
+func writeFilterChild(b *strings.Builder, expr FilterExpr) {
+	if expr.Op == FilterExists {
+		writeFilter(b, expr)
+		return
+	}
+
+	b.WriteRune('(')
+	writeFilter(b, expr)
+	b.WriteRune(')')
+}
+
+func writeOperand(b *strings.Builder, operand FilterOperand) {
+	if operand.Current || operand.Root {
+		if operand.Root {
+			b.WriteRune(RootElement)
+		} else {
+			b.WriteRune(CurrentElement)
+		}
+		for _, seg := range operand.Segments {
+			writeSegment(b, seg, false)
+		}
+		return
+	}
+
+	switch v := operand.Literal.(type) {
+	case nil:
+		b.WriteString("null")
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		bs, _ := json.Marshal(v)
+		b.Write(bs)
+	}
+}
+
+// String returns the alternative in its canonical form
+func (a Alternative) String() string {
+	var b strings.Builder
+	writeSegments(&b, a.Segments)
+	return b.String()
+}
+
+// writeFunctions writes the functions piped into the result of a path
+func writeFunctions(b *strings.Builder, functions []Function) {
+	for _, function := range functions {
+		b.WriteString(" " + string(AlternativeSeparator) + " " + function.Name)
+		if len(function.Args) > 0 {
+			b.WriteRune('(')
+			for i, arg := range function.Args {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				bs, _ := json.Marshal(arg)
+				b.Write(bs)
+			}
+			b.WriteRune(')')
+		}
+	}
+}
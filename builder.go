@@ -0,0 +1,87 @@
+package rjson
+
+import "slices"
+
+// Root starts building a path programmatically, e.g rjson.Root().Key("items").Each().Key("id")
+func Root() *Path {
+	return &Path{Alternatives: []Alternative{{}}}
+}
+
+// with returns a copy of the path with seg appended to its last alternative, the original is left untouched
+func (p *Path) with(seg Segment) *Path {
+	alternatives := slices.Clone(p.Alternatives)
+	if len(alternatives) == 0 {
+		alternatives = []Alternative{{}}
+	}
+
+	last := &alternatives[len(alternatives)-1]
+	last.Segments = append(slices.Clip(last.Segments), seg)
+
+	return &Path{Alternatives: alternatives}
+}
+
+// Key appends an object key, keys are quoted when the path is turned into a string if needed
+func (p *Path) Key(key string) *Path {
+	return p.with(Segment{Kind: KeySegment, Key: key})
+}
+
+// Index appends an array index, negative indexes count from the end
+func (p *Path) Index(i int) *Path {
+	return p.with(Segment{Kind: IndexSegment, Index: i})
+}
+
+// Last appends [-], the last element of an array
+func (p *Path) Last() *Path {
+	return p.with(Segment{Kind: LastSegment})
+}
+
+// Each appends [], the rest of the path is applied to every element of the array
+func (p *Path) Each() *Path {
+	return p.with(Segment{Kind: IteratorSegment})
+}
+
+// EachAligned appends []?, like Each but elements the rest of the path doesnt resolve for become null so results stay index aligned
+func (p *Path) EachAligned() *Path {
+	return p.with(Segment{Kind: IteratorSegment, Aligned: true})
+}
+
+// Slice appends a slice, nil bounds are left open
+func (p *Path) Slice(start, end, step *int) *Path {
+	return p.with(Segment{Kind: SliceSegment, Slice: SliceRange{Start: start, End: end, Step: step}})
+}
+
+// Filter appends a filter selecting the array elements matching expr
+func (p *Path) Filter(expr FilterExpr) *Path {
+	return p.with(Segment{Kind: FilterSegment, Filter: &expr})
+}
+
+// Wildcard appends *, the rest of the path is applied to every value of the object
+func (p *Path) Wildcard() *Path {
+	return p.with(Segment{Kind: WildcardSegment})
+}
+
+// Descend appends ..key, collecting the values of the key at any depth
+func (p *Path) Descend(key string) *Path {
+	return p.with(Segment{Kind: DescentSegment, Key: key})
+}
+
+// Pipe pipes the result of whichever alternative resolves into a builtin function, e.g Pipe("join", ", ")
+func (p *Path) Pipe(name string, args ...string) *Path {
+	alternatives := p.Alternatives
+	if len(alternatives) == 0 {
+		alternatives = []Alternative{{}}
+	}
+
+	return &Path{Alternatives: alternatives, Functions: append(slices.Clip(p.Functions), Function{Name: name, Args: args})}
+}
+
+// Or adds the alternatives of other as fallbacks tried when this path doesnt resolve.
+// The functions of p are kept, the ones of other are only used when p has none
+func (p *Path) Or(other *Path) *Path {
+	functions := p.Functions
+	if len(functions) == 0 {
+		functions = other.Functions
+	}
+
+	return &Path{Alternatives: append(slices.Clip(p.Alternatives), other.Alternatives...), Functions: functions}
+}
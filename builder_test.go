@@ -0,0 +1,84 @@
+package rjson
+
+import (
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+func TestCanonicalString(t *testing.T) {
+	paths := map[string]string{
+		"one.two.three":                 "one.two.three",
+		"arr[0][-1][-][]":               "arr[0][-1][-][]",
+		`headers["content-type"]`:       `headers."content-type"`,
+		`"a.b".c`:                       `"a.b".c`,
+		"jarray[1].\"1\"":               `jarray[1]."1"`,
+		"arr[1:] | arr[::-2] | a[:3]":   "arr[1:] | arr[::-2] | a[:3]",
+		"users.*.name":                  "users.*.name",
+		"users[*]":                      "users.*",
+		"..a.b..c":                      "..a.b..c",
+		"[0].name":                      "[0].name",
+		"a | length":                    "a | length",
+		`a | "length" | b | join(", ")`: `a | "length" | b | join(", ")`,
+		`f[?(@.a == "x" && (@.b > 1.5 || !@.c))][0]`:      `f[?((@.a == "x") && ((@.b > 1.5) || (!@.c)))][0]`,
+		`f[?(@ != null && @.x[0] <= -3 || @..y == true)]`: `f[?(((@ != null) && (@.x[0] <= -3)) || (@..y == true))]`,
+		"/items/0/a~1b":                      "/items/0/a~1b",
+		"$.config..url[0]":                   "$.config..url[0]",
+		"a[]?.b[1:]?.*?[*]?":                 "a[]?.b[1:]?.*?.*?",
+		"@[*]":                               "@.*",
+		`a[?(@.min <= $.limits["max-age"])]`: `a[?(@.min <= $.limits."max-age")]`,
+	}
+
+	assert.TestState = t
+	for path, canonical := range paths {
+		p, err := Compile(path)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		assert.Equals(p.String(), canonical)
+
+		reparsed, err := Compile(p.String())
+		if err != nil {
+			t.Fatalf("%s: %s", p, err)
+		}
+		assert.Equals(reparsed, p)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	assert.TestState = t
+
+	base := Root().Key("items")
+	ids := base.Each().Key("id")
+	first := base.Index(0).Key("content-type")
+
+	assert.Equals(ids.String(), "items[].id")
+	assert.Equals(first.String(), `items[0]."content-type"`)
+	assert.Equals(base.String(), "items")
+
+	start, step := 1, 2
+	assert.Equals(Root().Key("a.b").Slice(&start, nil, &step).Last().String(), `"a.b"[1::2][-]`)
+	assert.Equals(Root().Descend("runs").Wildcard().Key("length").String(), `..runs.*."length"`)
+	assert.Equals(Root().Key("a").EachAligned().Key("name").String(), "a[]?.name")
+	assert.Equals(Root().Key("a").Pipe("join", ", ").Or(Root().Key("b")).String(), `a | b | join(", ")`)
+
+	filter := FilterExpr{Op: FilterEq, Operands: []FilterOperand{
+		{Current: true, Segments: []Segment{{Kind: KeySegment, Key: "type"}}},
+		{Literal: "video"},
+	}}
+	assert.Equals(Root().Key("items").Filter(filter).Index(0).String(), `items[?(@.type == "video")][0]`)
+
+	data := []byte(`{"items": [{"id": 1, "content-type": "a"}, {"id": 2}]}`)
+	res, err := ids.Query(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(string(res), "[1,2]")
+
+	res, err = first.Query(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(string(res), `"a"`)
+}
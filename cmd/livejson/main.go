@@ -2,9 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/BatteredBunny/rjson"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,6 +21,8 @@ var (
 	bold   = color.New(color.Bold).SprintFunc()
 )
 
+const queryPrompt = "query>"
+
 func main() {
 	var jsonFile string
 	flag.StringVar(&jsonFile, "file", "", "Path to JSON file (required)")
@@ -104,17 +108,31 @@ func (m model) View() string {
 	var s string
 
 	s += fmt.Sprintf("%s - %s | Press q to quit\n", bold("RJSON repl"), green(m.jsonFile))
-	s += fmt.Sprintf("%s\n", executeQuery(m.query, m.jsonData))
 
-	s += fmt.Sprintf("%s %s", cyan("query>"), m.query)
+	output, err := executeQuery(m.query, m.jsonData)
+	s += fmt.Sprintf("%s\n", output)
+
+	s += fmt.Sprintf("%s %s", cyan(queryPrompt), m.query)
+
+	// Point at the character that broke the query
+	var parseErr *rjson.ParseError
+	if m.query != "" && errors.As(err, &parseErr) {
+		s += fmt.Sprintf("\n%s%s", strings.Repeat(" ", len(queryPrompt)+1), red(parseErr.Caret()))
+	}
+
+	// Offer the keys the query might have meant while its being typed
+	var queryErr *rjson.QueryError
+	if m.query != "" && errors.As(err, &queryErr) && len(queryErr.Suggestions) > 0 {
+		s += fmt.Sprintf("\n%s %s", yellow("did you mean:"), strings.Join(queryErr.Suggestions, ", "))
+	}
 
 	return s
 }
 
-func executeQuery(query string, jsonData []byte) string {
+func executeQuery(query string, jsonData []byte) (string, error) {
 	result, err := rjson.QueryJson(jsonData, query)
 	if err != nil {
-		return fmt.Sprintf("%s %v", red("Query Error:"), err)
+		return fmt.Sprintf("%s %v", red("Query Error:"), err), err
 	}
 
 	var output []byte
@@ -123,5 +141,5 @@ func executeQuery(query string, jsonData []byte) string {
 		output = result
 	}
 
-	return green(string(output))
+	return green(string(output)), nil
 }
@@ -0,0 +1,478 @@
+package rjson
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode"
+
+	"github.com/goccy/go-json"
+)
+
+// SetOption changes how Set stores a value
+type SetOption func(*editor)
+
+// CreateMissing makes Set create the objects missing on the way to the value instead of returning ErrCantFindField,
+// e.g context.client.hl on {} gives {"context":{"client":{"hl":...}}}. Only keys at the end of the path are created
+func CreateMissing() SetOption {
+	return func(ed *editor) {
+		ed.createMissing = true
+	}
+}
+
+// edit replaces data[start:end] with replacement, inserts have start and end at the same offset
+type edit struct {
+	start, end  int
+	replacement []byte
+}
+
+// editor collects the edits of a Set or Delete and applies them to a copy of the document, so the bytes it doesnt touch stay as they were
+type editor struct {
+	data          []byte
+	root          span   // Where the document is in data without the whitespace around it
+	value         []byte // Marshalled value of a Set
+	createMissing bool
+	edits         []edit
+	scanned       map[int][]item // Items of the containers already scanned by where they start
+}
+
+// span is where a value is in the document
+type span struct {
+	start, end int
+}
+
+// apply returns a copy of the document with the edits made, edits inside of a range another edit replaced are dropped since what they changed is gone
+func (ed *editor) apply() []byte {
+	// Outer edits come before the ones nested in them
+	slices.SortStableFunc(ed.edits, func(a, b edit) int {
+		return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(b.end, a.end))
+	})
+
+	out := make([]byte, 0, len(ed.data))
+	pos := 0
+	for _, e := range ed.edits {
+		if e.start < pos {
+			continue
+		}
+
+		out = append(out, ed.data[pos:e.start]...)
+		out = append(out, e.replacement...)
+		pos = e.end
+	}
+
+	return append(out, ed.data[pos:]...)
+}
+
+// item is where a member of an object or an element of an array is in the document, the start of members is the start of their key
+type item struct {
+	start int
+	value span
+	step  Segment // The key or index leading to the item
+}
+
+// items returns where the members or elements of the container are, containers are only scanned once
+func (ed *editor) items(container span) (items []item, err error) {
+	if items, ok := ed.scanned[container.start]; ok {
+		return items, nil
+	}
+
+	raw := ed.data[container.start:container.end]
+	if jsonKind(raw) == "array" {
+		err = eachElementAt(raw, func(start, end int) (bool, error) {
+			value := span{container.start + start, container.start + end}
+			items = append(items, item{start: value.start, value: value, step: indexStep(len(items))})
+			return true, nil
+		})
+	} else {
+		err = eachMemberAt(raw, func(k []byte, at, start, end int) (bool, error) {
+			key, err := decodeKey(k)
+			items = append(items, item{start: container.start + at, value: span{container.start + start, container.start + end}, step: keyStep(key)})
+			return true, err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ed.scanned[container.start] = items
+	return items, nil
+}
+
+// matching returns the items step leads to, every copy of a key thats there more than once
+func matching(items []item, step Segment) []item {
+	if step.Kind == IndexSegment {
+		// Elements are in order, members of an object dont have indexes
+		if step.Index < 0 || step.Index >= len(items) || items[step.Index].step != step {
+			return nil
+		}
+
+		return items[step.Index : step.Index+1]
+	}
+
+	var found []item
+	for _, it := range items {
+		if it.step == step {
+			found = append(found, it)
+		}
+	}
+
+	return found
+}
+
+// locate returns where the value at the concrete path is, keys that are there more than once lead to their last value like in queries
+func (ed *editor) locate(segments []Segment) (span, error) {
+	at := ed.root
+	for i, seg := range segments {
+		items, err := ed.items(at)
+		if err != nil {
+			return span{}, err
+		}
+
+		found := matching(items, seg)
+		if len(found) == 0 {
+			return span{}, fmt.Errorf("%w: %s isnt in the document", ErrCantFindField, Alternative{Segments: segments[:i+1]})
+		}
+
+		at = found[len(found)-1].value
+	}
+
+	return at, nil
+}
+
+// replace stores the value at the concrete path, every copy of a key thats there more than once is changed like Delete removes every copy
+func (ed *editor) replace(segments []Segment) error {
+	if len(segments) == 0 {
+		ed.edits = append(ed.edits, edit{start: ed.root.start, end: ed.root.end, replacement: ed.value})
+		return nil
+	}
+
+	parent, err := ed.locate(segments[:len(segments)-1])
+	if err != nil {
+		return err
+	}
+
+	items, err := ed.items(parent)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range matching(items, segments[len(segments)-1]) {
+		ed.edits = append(ed.edits, edit{start: it.value.start, end: it.value.end, replacement: ed.value})
+	}
+
+	return nil
+}
+
+// remove deletes the items of a container that match one of the steps, together with the commas and whitespace around them
+func (ed *editor) remove(container span, steps []Segment) error {
+	items, err := ed.items(container)
+	if err != nil {
+		return err
+	}
+
+	drop := make(map[Segment]bool, len(steps))
+	for _, step := range steps {
+		drop[step] = true
+	}
+
+	removed := make([]bool, len(items))
+	last := -1 // Last item that stays
+	for i, it := range items {
+		removed[i] = drop[it.step]
+		if !removed[i] {
+			last = i
+		}
+	}
+
+	// Items before one that stays take everything up to the next item with them
+	for i := range last {
+		if removed[i] {
+			ed.edits = append(ed.edits, edit{start: items[i].start, end: items[i+1].start})
+		}
+	}
+
+	// The removed items at the end take the comma after the last item that stays with them
+	if last < len(items)-1 {
+		start := items[0].start
+		if last >= 0 {
+			start = items[last].value.end
+		}
+
+		ed.edits = append(ed.edits, edit{start: start, end: items[len(items)-1].value.end})
+	}
+
+	return nil
+}
+
+// insert adds a member at the end of the object at the concrete path
+func (ed *editor) insert(segments []Segment, key string, value []byte) error {
+	object, err := ed.locate(segments)
+	if err != nil {
+		return err
+	}
+
+	items, err := ed.items(object)
+	if err != nil {
+		return err
+	}
+
+	member, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	member = append(append(member, ':'), value...)
+
+	// Put right after the last member so the formatting before the closing brace stays
+	if len(items) > 0 {
+		at := items[len(items)-1].value.end
+		ed.edits = append(ed.edits, edit{start: at, end: at, replacement: append([]byte{','}, member...)})
+		return nil
+	}
+
+	ed.edits = append(ed.edits, edit{start: object.start + 1, end: object.start + 1, replacement: member})
+	return nil
+}
+
+// nestValue wraps value in an object for every key, e.g a, b and 1 give {"a":{"b":1}}
+func nestValue(keys []string, value []byte) ([]byte, error) {
+	for _, key := range slices.Backward(keys) {
+		raw, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		value = append(append(append(append([]byte{'{'}, raw...), ':'), value...), '}')
+	}
+
+	return value, nil
+}
+
+// isKeyStep reports if the segment names a member of an object, pointer segments only know once they see the value
+func isKeyStep(seg Segment) bool {
+	return seg.Kind == KeySegment || seg.Kind == PointerSegment
+}
+
+// isConcrete reports if the segments lead to at most one value
+func isConcrete(segments []Segment) bool {
+	for _, seg := range segments {
+		switch seg.Kind {
+		case KeySegment, IndexSegment, LastSegment, PointerSegment, RootSegment, CurrentSegment:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// setKeys stores the value at the keys below m, offset is where the keys start in the path
+func (ed *editor) setKeys(e *evaluator, m match, keys []Segment, offset int) error {
+	object := m.value
+	for i, seg := range keys {
+		if seg.Kind == PointerSegment {
+			resolved, err := resolvePointerSegment(object, seg.Key)
+			if err != nil {
+				return failSegment(err, offset+i, seg, m)
+			}
+
+			seg = resolved
+		}
+
+		var child json.RawMessage
+		var err error
+		if seg.Kind == IndexSegment {
+			var at int
+			child, at, err = e.arrayElement(object, seg.Index)
+			seg = indexStep(at)
+		} else {
+			child, err = e.lookupKey(object, seg.Key)
+		}
+
+		// The last key is always added, the ones before it only when asked to
+		if errors.Is(err, ErrCantFindField) && (i == len(keys)-1 || ed.createMissing) {
+			var names []string
+			for _, rest := range keys[i+1:] {
+				names = append(names, rest.Key)
+			}
+
+			value, err := nestValue(names, ed.value)
+			if err != nil {
+				return err
+			}
+
+			return ed.insert(m.segments, seg.Key, value)
+		} else if err != nil {
+			return failSegment(err, offset+i, seg, m)
+		}
+
+		m = e.step(m, seg, child)
+		object = child
+	}
+
+	return ed.replace(m.segments)
+}
+
+// set stores the value at the end of the alternative, keys missing at the end are added to their object
+func (ed *editor) set(e *evaluator, alternative Alternative) error {
+	segments := alternative.Segments
+
+	// The keys at the end are resolved by hand since QueryAll only finds what is already there
+	keys := len(segments)
+	for keys > 0 && isKeyStep(segments[keys-1]) {
+		keys--
+	}
+
+	if keys == len(segments) {
+		found, err := e.run(e.root, segments)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range found.leaves() {
+			if err = ed.replace(m.segments); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	parents, err := e.run(e.root, segments[:keys])
+	if err != nil {
+		return err
+	}
+
+	// Like iterators, the parents that dont have the path are skipped
+	skipMissing := !isConcrete(segments[:keys])
+	for _, parent := range parents.leaves() {
+		if err = ed.setKeys(e, parent, segments[keys:], keys); skipMissing && isMissing(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// delete removes every value the alternative resolves to from the object or array its in
+func (ed *editor) delete(e *evaluator, alternative Alternative) error {
+	found, err := e.run(e.root, alternative.Segments)
+	if err != nil {
+		return err
+	}
+	matches := found.leaves()
+
+	// Values are removed through their container, the containers are found again by the concrete path of the values
+	type group struct {
+		parent []Segment
+		steps  []Segment
+	}
+
+	var groups []*group
+	byParent := map[string]*group{}
+	for _, m := range matches {
+		if len(m.segments) == 0 {
+			return fmt.Errorf("%w: the whole document cant be deleted", ErrMalformedSyntax)
+		}
+
+		parent := m.segments[:len(m.segments)-1]
+		id := Alternative{Segments: parent}.String()
+
+		g, ok := byParent[id]
+		if !ok {
+			g = &group{parent: parent}
+			byParent[id] = g
+			groups = append(groups, g)
+		}
+
+		g.steps = append(g.steps, m.segments[len(m.segments)-1])
+	}
+
+	for _, g := range groups {
+		container, err := ed.locate(g.parent)
+		if err != nil {
+			return err
+		}
+
+		if err = ed.remove(container, g.steps); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// edit runs fn against the first alternative that resolves and applies what it changed
+func (p *Path) edit(data []byte, ed *editor, name string, fn func(e *evaluator, alternative Alternative) error) (res []byte, err error) {
+	e, err := newEvaluator(data)
+	if err != nil {
+		return nil, err
+	}
+	e.track = true
+
+	// The document is found again by the concrete paths of the matches, so where it starts in data is needed
+	start := len(data) - len(bytes.TrimLeftFunc(data, unicode.IsSpace))
+	ed.data, ed.root = data, span{start, start + len(e.root)}
+	ed.scanned = map[int][]item{}
+
+	if len(p.Functions) > 0 {
+		return nil, fmt.Errorf("%w: functions cant be used with %s", ErrMalformedSyntax, name)
+	}
+
+	err = p.eachAlternative(func(alternative Alternative) error {
+		ed.edits = ed.edits[:0]
+		return fn(e, alternative)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ed.apply(), nil
+}
+
+// Set stores value at the path and returns the changed document, value is marshalled to json.
+// Every value an iterator goes through is set, e.g items[].flag, and a key missing at the end of the path is added to its object.
+// Key order, formatting and every byte outside of the changed values are kept
+func Set(data []byte, tag string, value any, opts ...SetOption) ([]byte, error) {
+	path, err := Compile(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+	}
+
+	return path.Set(data, value, opts...)
+}
+
+// Set stores value at the path, same as the Set function
+func (p *Path) Set(data []byte, value any, opts ...SetOption) ([]byte, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	ed := &editor{value: raw}
+	for _, opt := range opts {
+		opt(ed)
+	}
+
+	return p.edit(data, ed, "Set", ed.set)
+}
+
+// Delete removes every value the path resolves to from its object or array and returns the changed document, e.g items[-] or items[?(@.ad)].
+// Commas around the removed values are taken out with them, everything else is kept as is
+func Delete(data []byte, tag string) ([]byte, error) {
+	path, err := Compile(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+	}
+
+	return path.Delete(data)
+}
+
+// Delete removes every value the path resolves to, same as the Delete function
+func (p *Path) Delete(data []byte) ([]byte, error) {
+	ed := &editor{}
+	return p.edit(data, ed, "Delete", ed.delete)
+}
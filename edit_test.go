@@ -0,0 +1,153 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+	"github.com/goccy/go-json"
+)
+
+func TestSet(t *testing.T) {
+	data := []byte(`{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id": 2}, "text"],
+  "empty": {}
+}`)
+
+	cases := map[string]struct {
+		value    any
+		expected string
+	}{
+		"context.client.hl": {"de", `{
+  "context": {"client": {"hl": "de", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id": 2}, "text"],
+  "empty": {}
+}`},
+		"context.client.new": {1, `{
+  "context": {"client": {"hl": "en", "gl": "US","new":1}},
+  "items": [{"id": 1, "flag": false}, {"id": 2}, "text"],
+  "empty": {}
+}`},
+		"items[].flag": {true, `{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": true}, {"id": 2,"flag":true}, "text"],
+  "empty": {}
+}`},
+		"items[-]": {nil, `{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id": 2}, null],
+  "empty": {}
+}`},
+		"items[?(@.id > 1)]": {json.RawMessage(`{"id": 3}`), `{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id":3}, "text"],
+  "empty": {}
+}`},
+		`empty["a\"b"]`: {"x", `{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id": 2}, "text"],
+  "empty": {"a\"b":"x"}
+}`},
+		"/items/1/id": {5, `{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id": 5}, "text"],
+  "empty": {}
+}`},
+		"$": {[]int{1}, `[1]`},
+	}
+
+	assert.TestState = t
+	for tag, c := range cases {
+		res, err := Set(data, tag, c.value)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), c.expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, c.expected))
+	}
+
+	_, err := Set(data, "missing.key", 1)
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+
+	res, err := Set(data, "missing.deeper.key", 1, CreateMissing())
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `{
+  "context": {"client": {"hl": "en", "gl": "US"}},
+  "items": [{"id": 1, "flag": false}, {"id": 2}, "text"],
+  "empty": {},"missing":{"deeper":{"key":1}}
+}`)
+
+	res, err = Set([]byte(`{}`), "a.b", "c", CreateMissing())
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `{"a":{"b":"c"}}`)
+
+	// Every copy of a duplicate key is changed, the same way Delete removes every copy
+	res, err = Set([]byte(`{"a": 1, "b": {"a": 2}, "a": 3}`), "a", 4)
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `{"a": 4, "b": {"a": 2}, "a": 4}`)
+
+	// Keys before the end follow the last copy like queries do
+	res, err = Set([]byte(`{"a": {"b": 1}, "a": {"b": 2}}`), "a.b", 3)
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `{"a": {"b": 1}, "a": {"b": 3}}`)
+
+	res, err = Set([]byte("\n  [1, [2, 3]] \n"), "[1][-]", 4)
+	assert.Equals(err, nil)
+	assert.Equals(string(res), "\n  [1, [2, 4]] \n")
+
+	_, err = Set(data, "items[0] | length", 1)
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	_, err = Set([]byte(`{"a": }`), "a", 1)
+	assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("expected ErrInvalidJson, got %v", err))
+}
+
+func TestDelete(t *testing.T) {
+	data := []byte(`{"a": 1, "b": [1, 2, 3, 4], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`)
+
+	cases := map[string]string{
+		"a":                      `{"b": [1, 2, 3, 4], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"e":                      `{"a": 1, "b": [1, 2, 3, 4], "c": {"d": {"a": 2}}}`,
+		"b[-]":                   `{"a": 1, "b": [1, 2, 3], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"b[0]":                   `{"a": 1, "b": [2, 3, 4], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"b[1:3]":                 `{"a": 1, "b": [1, 4], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"b[2:]":                  `{"a": 1, "b": [1, 2], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"b[]":                    `{"a": 1, "b": [], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"e[?(@.ad == true)]":     `{"a": 1, "b": [1, 2, 3, 4], "c": {"d": {"a": 2}}, "e": [{"ad": false}]}`,
+		"e[].ad":                 `{"a": 1, "b": [1, 2, 3, 4], "c": {"d": {"a": 2}}, "e": [{}, {}, {}]}`,
+		"..a":                    `{"b": [1, 2, 3, 4], "c": {"d": {}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"c.*":                    `{"a": 1, "b": [1, 2, 3, 4], "c": {}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"missing | c.d":          `{"a": 1, "b": [1, 2, 3, 4], "c": {}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"/c/d/a":                 `{"a": 1, "b": [1, 2, 3, 4], "c": {"d": {}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+		"e[?(@.ad == \"none\")]": `{"a": 1, "b": [1, 2, 3, 4], "c": {"d": {"a": 2}}, "e": [{"ad": true}, {"ad": false}, {"ad": true}]}`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := Delete(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+		assert.Assert(json.Valid(res), fmt.Sprintf("%s: result isnt valid json", tag))
+	}
+
+	// Pretty printed documents keep their layout
+	res, err := Delete([]byte("{\n  \"a\": 1,\n  \"b\": 2\n}"), "b")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), "{\n  \"a\": 1\n}")
+
+	// Duplicate keys are all removed so the key is really gone
+	res, err = Delete([]byte(`{"a": 1, "b": 2, "a": 3}`), "a")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `{"b": 2}`)
+
+	_, err = Delete(data, "missing")
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+
+	_, err = Delete(data, "$")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+}
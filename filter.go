@@ -0,0 +1,148 @@
+package rjson
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+
+	"github.com/goccy/go-json"
+)
+
+// resolveOperand returns the decoded value of the operand and if it exists in element
+func (e *evaluator) resolveOperand(element json.RawMessage, operand FilterOperand) (value any, exists bool, err error) {
+	start := element
+	if operand.Root {
+		start = e.root
+	} else if !operand.Current {
+		return operand.Literal, true, nil
+	}
+
+	var raw json.RawMessage
+	if raw, err = e.executeSegments(start, operand.Segments); isUnresolved(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(raw, &value); err != nil {
+		return
+	}
+
+	return value, true, nil
+}
+
+// compareValues compares a and b, ordering is only defined between two numbers or two strings
+func compareValues(op FilterOp, a, b any) bool {
+	switch op {
+	case FilterEq:
+		return reflect.DeepEqual(a, b)
+	case FilterNe:
+		return !reflect.DeepEqual(a, b)
+	}
+
+	var res int
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		res = cmp.Compare(a, b)
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return false
+		}
+		res = cmp.Compare(a, b)
+	default:
+		return false
+	}
+
+	switch op {
+	case FilterLt:
+		return res < 0
+	case FilterLe:
+		return res <= 0
+	case FilterGt:
+		return res > 0
+	case FilterGe:
+		return res >= 0
+	}
+
+	return false
+}
+
+// filterArity returns how many children and operands an expression with op needs
+func filterArity(op FilterOp) (children, operands int) {
+	switch op {
+	case FilterAnd, FilterOr:
+		return 2, 0
+	case FilterNot:
+		return 1, 0
+	case FilterExists:
+		return 0, 1
+	}
+
+	return 0, 2
+}
+
+// matchFilter reports if element satisfies the filter expression
+func (e *evaluator) matchFilter(element json.RawMessage, expr FilterExpr) (bool, error) {
+	// Expressions made by hand can be missing parts the parser would have required
+	if children, operands := filterArity(expr.Op); len(expr.Children) < children || len(expr.Operands) < operands {
+		return false, fmt.Errorf("%w: filter is missing operands", ErrMalformedSyntax)
+	}
+
+	switch expr.Op {
+	case FilterAnd:
+		ok, err := e.matchFilter(element, expr.Children[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		return e.matchFilter(element, expr.Children[1])
+	case FilterOr:
+		ok, err := e.matchFilter(element, expr.Children[0])
+		if err != nil || ok {
+			return ok, err
+		}
+		return e.matchFilter(element, expr.Children[1])
+	case FilterNot:
+		ok, err := e.matchFilter(element, expr.Children[0])
+		return !ok, err
+	case FilterExists:
+		_, exists, err := e.resolveOperand(element, expr.Operands[0])
+		return exists, err
+	}
+
+	a, aExists, err := e.resolveOperand(element, expr.Operands[0])
+	if err != nil {
+		return false, err
+	}
+
+	b, bExists, err := e.resolveOperand(element, expr.Operands[1])
+	if err != nil {
+		return false, err
+	}
+
+	// Missing values are never equal to anything
+	if !aExists || !bExists {
+		return expr.Op == FilterNe, nil
+	}
+
+	return compareValues(expr.Op, a, b), nil
+}
+
+// filterMatches returns the elements matching the filter expression
+func (e *evaluator) filterMatches(elements []match, expr FilterExpr) (result []match, err error) {
+	result = []match{}
+	for _, element := range elements {
+		var ok bool
+		if ok, err = e.matchFilter(element.value, expr); err != nil {
+			return
+		} else if ok {
+			result = append(result, element)
+		}
+	}
+
+	return
+}
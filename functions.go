@@ -0,0 +1,239 @@
+package rjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-json"
+)
+
+var ErrInvalidFunctionInput = errors.New("invalid function input")
+
+type builtinFunction struct {
+	Args int // Amount of string arguments the function takes
+	Run  func(value json.RawMessage, args []string) (json.RawMessage, error)
+}
+
+// builtinFunctions can be piped into after a path, e.g items | length
+var builtinFunctions = map[string]builtinFunction{
+	"length":   {Run: lengthFunction},
+	"keys":     {Run: keysFunction},
+	"values":   {Run: valuesFunction},
+	"first":    {Run: firstFunction},
+	"last":     {Run: lastFunction},
+	"join":     {Args: 1, Run: joinFunction},
+	"split":    {Args: 1, Run: stringFunction("split", func(s string, args []string) any { return strings.Split(s, args[0]) })},
+	"lower":    {Run: stringFunction("lower", func(s string, _ []string) any { return strings.ToLower(s) })},
+	"upper":    {Run: stringFunction("upper", func(s string, _ []string) any { return strings.ToUpper(s) })},
+	"trim":     {Run: stringFunction("trim", func(s string, _ []string) any { return strings.TrimSpace(s) })},
+	"tostring": {Run: tostringFunction},
+	"tonumber": {Run: tonumberFunction},
+}
+
+// jsonKind returns a human readable name for the type of a json value
+func jsonKind(value json.RawMessage) string {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return "nothing"
+	}
+
+	switch trimmed[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+func functionInputError(name string, expected string, value json.RawMessage) error {
+	return fmt.Errorf("%w: %s expects %s, got %s", ErrInvalidFunctionInput, name, expected, jsonKind(value))
+}
+
+// applyFunction runs a piped function on value
+func applyFunction(value json.RawMessage, function Function) (json.RawMessage, error) {
+	builtin, ok := builtinFunctions[function.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown function %s", ErrMalformedSyntax, function.Name)
+	} else if len(function.Args) != builtin.Args {
+		return nil, fmt.Errorf("%w: %s expects %d arguments", ErrMalformedSyntax, function.Name, builtin.Args)
+	}
+
+	return builtin.Run(value, function.Args)
+}
+
+func lengthFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	switch jsonKind(value) {
+	case "array":
+		arr, err := decodeArray(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(len(arr))
+	case "object":
+		members, err := objectMembers(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(len(members))
+	case "string":
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, err
+		}
+		return json.Marshal(utf8.RuneCountInString(s))
+	case "null":
+		return json.Marshal(0)
+	}
+
+	return nil, functionInputError("length", "an array, object or string", value)
+}
+
+func keysFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	switch jsonKind(value) {
+	case "object":
+		members, err := objectMembers(value)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, len(members))
+		for i, m := range members {
+			keys[i] = m.Key
+		}
+		return json.Marshal(keys)
+	case "array":
+		// Same as jq, the keys of an array are its indexes
+		arr, err := decodeArray(value)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]int, len(arr))
+		for i := range arr {
+			keys[i] = i
+		}
+		return json.Marshal(keys)
+	}
+
+	return nil, functionInputError("keys", "an object or array", value)
+}
+
+func valuesFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	switch jsonKind(value) {
+	case "object", "array":
+		values, err := wildcardValues(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return joinArray(values), nil
+	}
+
+	return nil, functionInputError("values", "an object or array", value)
+}
+
+func firstFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	if jsonKind(value) != "array" {
+		return nil, functionInputError("first", "an array", value)
+	}
+
+	element, _, err := arrayElement(value, 0)
+	return element, err
+}
+
+func lastFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	if jsonKind(value) != "array" {
+		return nil, functionInputError("last", "an array", value)
+	}
+
+	element, _, err := arrayElement(value, -1)
+	return element, err
+}
+
+func joinFunction(value json.RawMessage, args []string) (json.RawMessage, error) {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(value, &arr); err != nil {
+		return nil, functionInputError("join", "an array", value)
+	}
+
+	parts := make([]string, len(arr))
+	for i, element := range arr {
+		switch jsonKind(element) {
+		case "string":
+			if err := json.Unmarshal(element, &parts[i]); err != nil {
+				return nil, err
+			}
+		case "number", "boolean":
+			parts[i] = string(bytes.TrimSpace(element))
+		case "null":
+			parts[i] = ""
+		default:
+			return nil, functionInputError("join", "an array of plain values", element)
+		}
+	}
+
+	return json.Marshal(strings.Join(parts, args[0]))
+}
+
+// stringFunction wraps a function working on a decoded string
+func stringFunction(name string, fn func(s string, args []string) any) func(json.RawMessage, []string) (json.RawMessage, error) {
+	return func(value json.RawMessage, args []string) (json.RawMessage, error) {
+		var s string
+		if jsonKind(value) != "string" || json.Unmarshal(value, &s) != nil {
+			return nil, functionInputError(name, "a string", value)
+		}
+
+		return json.Marshal(fn(s, args))
+	}
+}
+
+func tostringFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	if jsonKind(value) == "string" {
+		return value, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(buf.String())
+}
+
+func tonumberFunction(value json.RawMessage, _ []string) (json.RawMessage, error) {
+	switch jsonKind(value) {
+	case "number":
+		return value, nil
+	case "string":
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, err
+		}
+
+		// Valid json numbers are kept as written, going through a float64 would round big integers
+		trimmed := strings.TrimSpace(s)
+		if raw := json.RawMessage(trimmed); jsonKind(raw) == "number" && json.Valid(raw) {
+			return raw, nil
+		}
+
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tonumber cant parse %q", ErrInvalidFunctionInput, s)
+		}
+		return json.Marshal(f)
+	}
+
+	return nil, functionInputError("tonumber", "a number or string", value)
+}
@@ -0,0 +1,106 @@
+package rjson
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// index remembers the members and elements of every container a document has been walked through,
+// Unmarshal shares one between all of its fields so a prefix used by many tags is only scanned once
+type index struct {
+	// Values are slices of the document, so the address of their first byte identifies them
+	containers map[*byte]*container
+}
+
+type container struct {
+	keys   [][]byte          // Raw quoted keys of an object, decoded only when compared. Each key is there once
+	values []json.RawMessage // Values of an object in the same order as keys, or the elements of an array
+}
+
+func newIndex() *index {
+	return &index{containers: make(map[*byte]*container)}
+}
+
+// container returns the indexed form of value if its of the given kind, nil means the caller should scan value itself
+func (ix *index) container(value json.RawMessage, kind string) *container {
+	if ix == nil || len(value) == 0 || jsonKind(value) != kind {
+		return nil
+	}
+
+	if c, ok := ix.containers[&value[0]]; ok {
+		return c
+	}
+
+	c := &container{}
+	switch kind {
+	case "object":
+		var err error
+		if c.keys, c.values, err = uniqueMembers(value); err != nil {
+			return nil
+		}
+	case "array":
+		elements, err := decodeArray(value)
+		if err != nil {
+			return nil
+		}
+
+		c.values = elements
+	}
+
+	ix.containers[&value[0]] = c
+	return c
+}
+
+func (e *evaluator) lookupKey(object json.RawMessage, key string) (json.RawMessage, error) {
+	c := e.index.container(object, "object")
+	if c == nil {
+		return lookupKey(object, key)
+	}
+
+	for i, k := range c.keys {
+		if ok, err := keyEquals(k, key); err != nil {
+			return nil, err
+		} else if ok {
+			return c.values[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %s", ErrCantFindField, key)
+}
+
+func (e *evaluator) decodeArray(object json.RawMessage) ([]json.RawMessage, error) {
+	c := e.index.container(object, "array")
+	if c == nil {
+		return decodeArray(object)
+	}
+
+	return c.values, nil
+}
+
+func (e *evaluator) arrayElement(object json.RawMessage, i int) (json.RawMessage, int, error) {
+	c := e.index.container(object, "array")
+	if c == nil {
+		return arrayElement(object, i)
+	}
+
+	at, err := elementIndex(i, len(c.values))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return c.values[at], at, nil
+}
+
+func (e *evaluator) wildcardValues(object json.RawMessage) ([]json.RawMessage, error) {
+	if jsonKind(object) == "array" {
+		return e.decodeArray(object)
+	}
+
+	c := e.index.container(object, "object")
+	if c == nil {
+		return wildcardValues(object)
+	}
+
+	return c.values, nil
+}
@@ -3,8 +3,11 @@ package rjson
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/goccy/go-json"
 )
 
 type lexer struct {
@@ -12,12 +15,25 @@ type lexer struct {
 	pos   int
 	start int
 	width int
+
+	tokenStart int         // Offset of the token being lexed, used for error positions
+	err        *ParseError // Set once the parser reports a syntax error
+	result     *Path       // Set by the parser once the whole path is parsed
 }
 
 const Divider = '.' // Path divider
 const ArrayOpen = '['
 const ArrayClose = ']'
-const ArrayLast = '-' // Takes last element in array
+const ArrayLast = '-'            // Takes last element in array
+const SliceSeparator = ':'       // Separates slice bounds, e.g [1:5:2]
+const Wildcard = '*'             // Iterates over the values of an object
+const FilterStart = '?'          // Starts a filter predicate, e.g [?(@.type == "video")]
+const AlignMarker = '?'          // After [], * or a slice keeps missing results as null so results stay index aligned, e.g arr[]?.name
+const CurrentElement = '@'       // Refers to the current element, the one being filtered or decoded
+const RootElement = '$'          // Refers to the root of the document, e.g $.config.baseUrl
+const AlternativeSeparator = '|' // Separates fallback paths, e.g a.b | c.d
+const KeyQuote = '"'             // Quotes keys that contain special characters, e.g "content-type"
+const KeyEscape = '\\'           // Escapes characters inside of a quoted key
 
 func newLexer(input string) *lexer {
 	return &lexer{
@@ -46,6 +62,15 @@ func (l *lexer) peek() rune {
 	return r
 }
 
+// accept consumes the next rune if its r
+func (l *lexer) accept(r rune) bool {
+	if l.next() == r {
+		return true
+	}
+	l.backup()
+	return false
+}
+
 func (l *lexer) ignore() {
 	l.start = l.pos
 }
@@ -54,9 +79,20 @@ func (l *lexer) emit(t int, lval *yySymType) int {
 	switch t {
 	case IDENTIFIER:
 		lval.str = l.input[l.start:l.pos]
+	case STRING:
+		// Quoted keys follow the json string rules so escapes behave the same as in the document
+		if err := json.Unmarshal([]byte(l.input[l.start:l.pos]), &lval.str); err != nil {
+			l.start = l.pos
+			return int(KeyQuote)
+		}
 	case NUMBER:
 		val, _ := strconv.Atoi(l.input[l.start:l.pos])
 		lval.num = val
+	case FLOAT:
+		val, _ := strconv.ParseFloat(l.input[l.start:l.pos], 64)
+		lval.float = val
+	case TRUE, FALSE, NULL, FUNCTION:
+		lval.str = l.input[l.start:l.pos]
 	}
 	l.start = l.pos
 	return t
@@ -64,6 +100,7 @@ func (l *lexer) emit(t int, lval *yySymType) int {
 
 func (l *lexer) Lex(lval *yySymType) int {
 	for {
+		l.tokenStart = l.pos
 		r := l.next()
 		if r == 0 {
 			return 0 // EOF
@@ -73,6 +110,11 @@ func (l *lexer) Lex(lval *yySymType) int {
 		case ' ', '\t', '\n', '\r':
 			l.ignore()
 		case Divider:
+			// Two dividers in a row descend recursively, e.g ..key
+			if l.accept(Divider) {
+				l.ignore()
+				return DESCENT
+			}
 			l.ignore()
 			return DOT
 		case ArrayOpen:
@@ -84,6 +126,72 @@ func (l *lexer) Lex(lval *yySymType) int {
 		case ArrayLast:
 			l.ignore()
 			return MINUS
+		case SliceSeparator:
+			l.ignore()
+			return COLON
+		case KeyQuote:
+			return l.lexString(lval)
+		case Wildcard:
+			l.ignore()
+			return STAR
+		case FilterStart:
+			l.ignore()
+			return QUESTION
+		case CurrentElement:
+			l.ignore()
+			return AT
+		case RootElement:
+			l.ignore()
+			return ROOT
+		case '(':
+			l.ignore()
+			return LPAREN
+		case ')':
+			l.ignore()
+			return RPAREN
+		case '=':
+			if l.accept('=') {
+				l.ignore()
+				return EQ
+			}
+			return int(r)
+		case '!':
+			if l.accept('=') {
+				l.ignore()
+				return NE
+			}
+			l.ignore()
+			return NOT
+		case '<':
+			if l.accept('=') {
+				l.ignore()
+				return LE
+			}
+			l.ignore()
+			return LT
+		case '>':
+			if l.accept('=') {
+				l.ignore()
+				return GE
+			}
+			l.ignore()
+			return GT
+		case '&':
+			if l.accept('&') {
+				l.ignore()
+				return AND
+			}
+			return int(r)
+		case AlternativeSeparator:
+			if l.accept(AlternativeSeparator) {
+				l.ignore()
+				return OR
+			}
+			if function, ok := l.lexFunction(lval); ok {
+				return function
+			}
+			l.ignore()
+			return PIPE
 		default:
 			if unicode.IsLetter(r) {
 				return l.lexIdentifier(lval)
@@ -103,9 +211,67 @@ func (l *lexer) lexIdentifier(lval *yySymType) int {
 		}
 		l.next()
 	}
+
+	// Keywords used by filter literals, the parser still accepts them as keys
+	switch l.input[l.start:l.pos] {
+	case "true":
+		return l.emit(TRUE, lval)
+	case "false":
+		return l.emit(FALSE, lval)
+	case "null":
+		return l.emit(NULL, lval)
+	}
+
 	return l.emit(IDENTIFIER, lval)
 }
 
+func (l *lexer) lexString(lval *yySymType) int {
+	for {
+		switch l.next() {
+		case 0:
+			return int(KeyQuote) // Unterminated key
+		case KeyEscape:
+			l.next()
+		case KeyQuote:
+			return l.emit(STRING, lval)
+		}
+	}
+}
+
+// lexFunction checks if the pipe is followed by a builtin function instead of another path, e.g | length
+func (l *lexer) lexFunction(lval *yySymType) (int, bool) {
+	start := l.pos
+	for start < len(l.input) && unicode.IsSpace(rune(l.input[start])) {
+		start++
+	}
+
+	end := start
+	for end < len(l.input) {
+		r, w := utf8.DecodeRuneInString(l.input[end:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		end += w
+	}
+
+	if _, ok := builtinFunctions[l.input[start:end]]; !ok {
+		return 0, false
+	}
+
+	// A name the path goes on from is a key of the next alternative, e.g first.name or keys[0]
+	next := end
+	for next < len(l.input) && unicode.IsSpace(rune(l.input[next])) {
+		next++
+	}
+
+	if next < len(l.input) && (l.input[next] == '.' || l.input[next] == '[') {
+		return 0, false
+	}
+
+	l.start, l.pos = start, end
+	return l.emit(FUNCTION, lval), true
+}
+
 func (l *lexer) lexNumber(lval *yySymType) int {
 	for {
 		r := l.peek()
@@ -114,11 +280,111 @@ func (l *lexer) lexNumber(lval *yySymType) int {
 		}
 		l.next()
 	}
+
+	// Fractions are only valid in filter literals, e.g 10.5
+	if l.peek() == '.' && l.pos+1 < len(l.input) && unicode.IsDigit(rune(l.input[l.pos+1])) {
+		l.next()
+		for unicode.IsDigit(l.peek()) {
+			l.next()
+		}
+		return l.emit(FLOAT, lval)
+	}
+
 	return l.emit(NUMBER, lval)
 }
 
+// ParseError describes where and why a path failed to parse
+type ParseError struct {
+	Path     string   // The path that was being parsed
+	Offset   int      // Byte offset of the unexpected token in Path
+	Token    string   // The unexpected token as written in Path, empty at the end of the path
+	Expected []string // Tokens that would have been valid instead, can be empty if there are too many options
+}
+
+func (e *ParseError) Error() string {
+	token := fmt.Sprintf("%q", e.Token)
+	if e.Token == "" {
+		token = "end of path"
+	}
+
+	msg := fmt.Sprintf("%s: unexpected %s at offset %d in %q", ErrMalformedSyntax, token, e.Offset, e.Path)
+	if len(e.Expected) > 0 {
+		msg += ", expected " + strings.Join(e.Expected, " or ")
+	}
+
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrMalformedSyntax
+}
+
+// Caret returns a line with a caret under the unexpected token, meant to be printed under the path
+func (e *ParseError) Caret() string {
+	return strings.Repeat(" ", utf8.RuneCountInString(e.Path[:e.Offset])) + "^"
+}
+
+// tokenDescriptions are the readable names of the grammar tokens used in errors
+var tokenDescriptions = map[string]string{
+	"$end":       "end of path",
+	"IDENTIFIER": "key",
+	"STRING":     "quoted key",
+	"TRUE":       "true",
+	"FALSE":      "false",
+	"NULL":       "null",
+	"FUNCTION":   "function",
+	"NUMBER":     "number",
+	"FLOAT":      "number",
+	"DOT":        string(Divider),
+	"DESCENT":    "..",
+	"LBRACKET":   string(ArrayOpen),
+	"RBRACKET":   string(ArrayClose),
+	"MINUS":      string(ArrayLast),
+	"COLON":      string(SliceSeparator),
+	"STAR":       string(Wildcard),
+	"PIPE":       string(AlternativeSeparator),
+	"QUESTION":   string(FilterStart),
+	"LPAREN":     "(",
+	"RPAREN":     ")",
+	"AT":         string(CurrentElement),
+	"ROOT":       string(RootElement),
+	"EQ":         "==",
+	"NE":         "!=",
+	"LT":         "<",
+	"LE":         "<=",
+	"GT":         ">",
+	"GE":         ">=",
+	"AND":        "&&",
+	"OR":         "||",
+	"NOT":        "!",
+}
+
+// expectedTokens extracts the expected tokens from a verbose goyacc error message
+func expectedTokens(s string) (expected []string) {
+	_, list, ok := strings.Cut(s, ", expecting ")
+	if !ok {
+		return
+	}
+
+	for _, name := range strings.Split(list, " or ") {
+		if description, ok := tokenDescriptions[name]; ok {
+			name = description
+		}
+		expected = append(expected, name)
+	}
+
+	return
+}
+
 func (l *lexer) Error(s string) {
 	if Debug {
 		fmt.Printf("Parse error: %s\n", s)
 	}
+
+	l.err = &ParseError{
+		Path:     l.input,
+		Offset:   l.tokenStart,
+		Token:    l.input[l.tokenStart:l.pos],
+		Expected: expectedTokens(s),
+	}
 }
@@ -0,0 +1,106 @@
+package rjson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/goccy/go-json"
+)
+
+// LineError is the error of a single line of newline delimited json, the other lines are still processed
+type LineError struct {
+	Line int // Starts from 1, empty lines are counted too
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// eachLine calls fn with every non empty line of r and its number, a failing read ends it
+func eachLine(r io.Reader, fn func(n int, line []byte) bool) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 && !fn(n, line) {
+			return nil
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// Lines decodes newline delimited json (ndjson, json lines) from r, yielding one T per line with the tags applied the same way Unmarshal does.
+// Lines that cant be decoded yield a *LineError and the lines after them are still decoded, empty lines are skipped
+func Lines[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := eachLine(r, func(n int, line []byte) bool {
+			var v T
+			if err := Unmarshal(line, &v); err != nil {
+				return yield(v, &LineError{Line: n, Err: err})
+			}
+
+			return yield(v, nil)
+		})
+
+		if err != nil {
+			var v T
+			yield(v, err)
+		}
+	}
+}
+
+// UnmarshalLines decodes every line of newline delimited json from r into a slice.
+// Lines that cant be decoded are left out, their errors are joined into the returned error next to the lines that could be decoded
+func UnmarshalLines[T any](r io.Reader) (values []T, err error) {
+	var errs []error
+	for v, err := range Lines[T](r) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		values = append(values, v)
+	}
+
+	return values, errors.Join(errs...)
+}
+
+// QueryLines runs the path against every line of newline delimited json from r, same as QueryJson.
+// Lines the path cant be resolved for yield a *LineError and the lines after them are still queried
+func QueryLines(r io.Reader, tag string) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		path, err := Compile(tag)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to parse tag '%s': %w", tag, err))
+			return
+		}
+
+		err = eachLine(r, func(n int, line []byte) bool {
+			res, err := path.Query(line)
+			if err != nil {
+				return yield(nil, &LineError{Line: n, Err: err})
+			}
+
+			return yield(res, nil)
+		})
+
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+const testLines = `{"user": {"name": "a"}, "status": 200}
+{"user": {"name": "b"}, "status": 404}
+
+{"user": {"name": "c"}, "status": 500
+{"status": 201}
+not json
+{"user": {"name": "d"}, "status": 200}`
+
+type logLine struct {
+	Name   string `rjson:"user.name"`
+	Status int    `rjson:"status"`
+}
+
+func TestLines(t *testing.T) {
+	var values []logLine
+	var lineErrors []int
+
+	for v, err := range Lines[logLine](strings.NewReader(testLines)) {
+		var lineErr *LineError
+		if errors.As(err, &lineErr) {
+			lineErrors = append(lineErrors, lineErr.Line)
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		values = append(values, v)
+	}
+
+	assert.TestState = t
+	assert.Equals(values, []logLine{{"a", 200}, {"b", 404}, {"", 201}, {"d", 200}})
+	assert.Equals(lineErrors, []int{4, 6})
+
+	// Stopping early
+	for v := range Lines[logLine](strings.NewReader(testLines)) {
+		assert.Equals(v.Name, "a")
+		break
+	}
+}
+
+func TestUnmarshalLines(t *testing.T) {
+	values, err := UnmarshalLines[logLine](strings.NewReader(testLines))
+
+	assert.TestState = t
+	assert.Equals(len(values), 4)
+	assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("expected ErrInvalidJson, got %v", err))
+	assert.Assert(strings.Contains(err.Error(), "line 4: ") && strings.Contains(err.Error(), "line 6: "), err.Error())
+
+	values, err = UnmarshalLines[logLine](strings.NewReader("{\"status\": 1}\r\n\r\n{\"status\": 2}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(values, []logLine{{Status: 1}, {Status: 2}})
+}
+
+func TestQueryLines(t *testing.T) {
+	var names []string
+	var missing []error
+
+	for res, err := range QueryLines(strings.NewReader(testLines), "user.name") {
+		if err != nil {
+			missing = append(missing, err)
+			continue
+		}
+
+		names = append(names, string(res))
+	}
+
+	assert.TestState = t
+	assert.Equals(names, []string{`"a"`, `"b"`, `"d"`})
+	assert.Equals(len(missing), 3)
+
+	var lineErr *LineError
+	assert.Assert(errors.As(missing[1], &lineErr) && lineErr.Line == 5, fmt.Sprintf("expected an error for line 5, got %v", missing[1]))
+	assert.Assert(errors.Is(missing[1], ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", missing[1]))
+
+	for _, err := range QueryLines(strings.NewReader(testLines), "user[") {
+		assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+	}
+}
@@ -0,0 +1,342 @@
+package rjson
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/goccy/go-json"
+)
+
+// Match is a single value a path resolved to
+type Match struct {
+	Path  *Path // Concrete path of the value made of keys and indexes only, e.g items[3].title
+	Value json.RawMessage
+}
+
+// match is a value with the segments leading to it, the segments are only kept when the evaluator tracks paths.
+// Values put together by iterators, filters and descents keep the matches they were made of when tracked,
+// so the segments after them lead back to where the values are in the document
+type match struct {
+	segments []Segment
+	value    json.RawMessage
+	elements []match
+	joined   bool
+}
+
+func keyStep(key string) Segment {
+	return Segment{Kind: KeySegment, Key: key}
+}
+
+func indexStep(i int) Segment {
+	return Segment{Kind: IndexSegment, Index: i}
+}
+
+// step returns the value seg leads to from m
+func (e *evaluator) step(m match, seg Segment, value json.RawMessage) match {
+	if !e.track {
+		return match{value: value}
+	}
+
+	return match{segments: append(slices.Clip(m.segments), seg), value: value}
+}
+
+// concat joins two paths when they are tracked
+func (e *evaluator) concat(a, b []Segment) []Segment {
+	if !e.track {
+		return nil
+	}
+
+	return slices.Concat(a, b)
+}
+
+// join puts the matches seg selected from m into an array
+func (e *evaluator) join(m match, seg Segment, matches []match) match {
+	values := make([]json.RawMessage, len(matches))
+	for i, found := range matches {
+		values[i] = found.value
+	}
+
+	return e.joinValues(m, seg, values, matches)
+}
+
+// joinValues is join for callers that have the values already, matches are only kept when paths are tracked
+func (e *evaluator) joinValues(m match, seg Segment, values []json.RawMessage, matches []match) match {
+	joined := match{value: joinArray(values)}
+	if e.track {
+		joined.segments = append(slices.Clip(m.segments), seg)
+		joined.elements, joined.joined = matches, true
+	}
+
+	return joined
+}
+
+// leaves returns the values the iterators, filters and descents of a tracked match went through
+func (m match) leaves() []match {
+	if !m.joined {
+		return []match{m}
+	}
+
+	leaves := []match{}
+	for _, element := range m.elements {
+		leaves = append(leaves, element.leaves()...)
+	}
+
+	return leaves
+}
+
+// elements returns the elements of an array as matches
+func (e *evaluator) elements(m match) ([]match, error) {
+	if m.joined {
+		return m.elements, nil
+	}
+
+	arr, err := e.decodeArray(m.value)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]match, len(arr))
+	for i, element := range arr {
+		matches[i] = e.step(m, indexStep(i), element)
+	}
+
+	return matches, nil
+}
+
+// values returns the values of an object or the elements of an array as matches, keys are only decoded when paths are tracked
+func (e *evaluator) values(m match) ([]match, error) {
+	if m.joined || jsonKind(m.value) == "array" {
+		return e.elements(m)
+	}
+
+	if !e.track {
+		values, err := e.wildcardValues(m.value)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := make([]match, len(values))
+		for i, value := range values {
+			matches[i] = match{value: value}
+		}
+
+		return matches, nil
+	}
+
+	members, err := objectMembers(m.value)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]match, len(members))
+	for i, member := range members {
+		matches[i] = e.step(m, keyStep(member.Key), member.Value)
+	}
+
+	return matches, nil
+}
+
+// descendants collects every value stored under key at any depth in document order, the document is only walked once
+func (e *evaluator) descendants(m match, key string) ([]match, error) {
+	d := &descent{e: e, key: key}
+
+	var walk func(m match) error
+	walk = func(m match) error {
+		// Values put together before are walked where they are in the document
+		if m.joined {
+			for _, element := range m.elements {
+				if err := walk(element); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		d.data = m.value
+		if i := skipSpace(m.value, 0); i < len(m.value) {
+			_, err := d.walk(m.segments, i)
+			return err
+		}
+
+		return nil
+	}
+
+	err := walk(m)
+	return d.found, err
+}
+
+// descent is a walk through a value looking for a key at any depth, the values under it are collected in found
+type descent struct {
+	e     *evaluator
+	key   string
+	data  []byte
+	found []match
+}
+
+// walk goes through the value starting at i that path leads to and returns where it ends,
+// containers learn where their values end by walking them so nothing is scanned twice
+func (d *descent) walk(path []Segment, i int) (int, error) {
+	if i >= len(d.data) {
+		return 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidJson)
+	}
+
+	switch d.data[i] {
+	case '{':
+		return d.walkObject(path, i)
+	case '[':
+		return d.walkArray(path, i)
+	case '"':
+		return stringEnd(d.data, i)
+	}
+
+	return scalarEnd(d.data, i)
+}
+
+// walkObject walks the members of the object starting at i. A key thats there more than once is kept at its
+// first position with what was found in its last copy, like uniqueMembers keeps it
+func (d *descent) walkObject(path []Segment, i int) (int, error) {
+	var seen memberKeys
+	var found [][2]int // Part of d.found that came from each key
+	base, moved := len(d.found), false
+
+	for i = skipSpace(d.data, i+1); i < len(d.data) && d.data[i] != '}'; {
+		start, err := memberStart(d.data, i)
+		if err != nil {
+			return 0, err
+		}
+
+		keyEnd, _ := stringEnd(d.data, i)
+		k := d.data[i:keyEnd]
+
+		var child []Segment
+		if d.e.track {
+			name, err := decodeKey(k)
+			if err != nil {
+				return 0, err
+			}
+			child = append(slices.Clip(path), keyStep(name))
+		}
+
+		// A match is collected before the matches nested inside of it to keep document order
+		from := len(d.found)
+		matched, err := keyEquals(k, d.key)
+		if err != nil {
+			return 0, err
+		} else if matched {
+			d.found = append(d.found, match{segments: child})
+		}
+
+		end, err := d.walk(child, start)
+		if err != nil {
+			return 0, err
+		}
+
+		if matched {
+			d.found[from].value = d.data[start:end:end]
+		}
+
+		if at, dup, err := seen.add(k); err != nil {
+			return 0, err
+		} else if dup {
+			found[at], moved = [2]int{from, len(d.found)}, true
+		} else {
+			found = append(found, [2]int{from, len(d.found)})
+		}
+
+		if i, err = nextItem(d.data, end, '}'); err != nil {
+			return 0, err
+		}
+	}
+
+	if i >= len(d.data) {
+		return 0, fmt.Errorf("%w: unterminated object", ErrInvalidJson)
+	}
+
+	// The last copy of a key takes the place of the first one
+	if moved {
+		walked := slices.Clone(d.found[base:])
+		d.found = d.found[:base]
+		for _, part := range found {
+			d.found = append(d.found, walked[part[0]-base:part[1]-base]...)
+		}
+	}
+
+	return i + 1, nil
+}
+
+// walkArray walks the elements of the array starting at i
+func (d *descent) walkArray(path []Segment, i int) (int, error) {
+	n := 0
+	for i = skipSpace(d.data, i+1); i < len(d.data) && d.data[i] != ']'; n++ {
+		var child []Segment
+		if d.e.track {
+			child = append(slices.Clip(path), indexStep(n))
+		}
+
+		end, err := d.walk(child, i)
+		if err != nil {
+			return 0, err
+		}
+
+		if i, err = nextItem(d.data, end, ']'); err != nil {
+			return 0, err
+		}
+	}
+
+	if i >= len(d.data) {
+		return 0, fmt.Errorf("%w: unterminated array", ErrInvalidJson)
+	}
+
+	return i + 1, nil
+}
+
+// resolvePointerSegment is resolvePointerSegment for matches, values put together by the evaluator are arrays
+func (e *evaluator) resolvePointerSegment(m match, key string) (Segment, error) {
+	if m.joined {
+		return resolvePointerSegment(json.RawMessage("[]"), key)
+	}
+
+	return resolvePointerSegment(m.value, key)
+}
+
+// QueryAll runs the path against json data and returns every value it resolved to with its concrete path,
+// instead of putting the values an iterator went through into one array like QueryJson does
+func QueryAll(data []byte, tag string) ([]Match, error) {
+	path, err := Compile(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+	}
+
+	return path.QueryAll(data)
+}
+
+// QueryAll runs the path against json data, same as the QueryAll function
+func (p *Path) QueryAll(data []byte) (matches []Match, err error) {
+	e, err := newEvaluator(data)
+	if err != nil {
+		return nil, err
+	}
+	e.track = true
+
+	if len(p.Functions) > 0 {
+		return nil, fmt.Errorf("%w: functions cant be used with QueryAll", ErrMalformedSyntax)
+	}
+
+	err = p.eachAlternative(func(alternative Alternative) error {
+		found, err := e.run(e.root, alternative.Segments)
+		if err != nil {
+			return err
+		}
+
+		leaves := found.leaves()
+		matches = make([]Match, len(leaves))
+		for i, m := range leaves {
+			matches[i] = Match{Path: &Path{Alternatives: []Alternative{{Segments: m.segments}}}, Value: m.value}
+		}
+
+		return nil
+	})
+
+	return
+}
@@ -0,0 +1,77 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+func TestQueryAll(t *testing.T) {
+	data := []byte(`{
+		"items": [
+			{"title": "a", "tags": ["x", "y"]},
+			{"tags": []},
+			{"title": "c", "tags": ["z"]}
+		],
+		"users": {"u1": {"name": "n1"}, "u2": {"name": "n2"}},
+		"nested": {"id": 1, "child": {"id": 2}}
+	}`)
+
+	cases := map[string][]string{
+		"items[].title":                {`items[0].title = "a"`, `items[2].title = "c"`},
+		"items[-].title":               {`items[2].title = "c"`},
+		"items[].tags[]":               {`items[0].tags[0] = "x"`, `items[0].tags[1] = "y"`, `items[2].tags[0] = "z"`},
+		"items[].tags[-1]":             {`items[0].tags[1] = "y"`, `items[2].tags[0] = "z"`},
+		"items[1:].title":              {`items[2].title = "c"`},
+		"users.*.name":                 {`users.u1.name = "n1"`, `users.u2.name = "n2"`},
+		`items[?(@.title == "c")]`:     {`items[2] = {"title": "c", "tags": ["z"]}`},
+		`items[?(@.title)][-].tags[0]`: {`items[2].tags[0] = "z"`},
+		"..id":                         {`nested.id = 1`, `nested.child.id = 2`},
+		"..id[-]":                      {`nested.child.id = 2`},
+		"/items/0/title":               {`items[0].title = "a"`},
+		"missing | items[0].title":     {`items[0].title = "a"`},
+		`$.users["u2"]`:                {`users.u2 = {"name": "n2"}`},
+		"items[]?.title":               {`items[0].title = "a"`, `items[1].title = null`, `items[2].title = "c"`},
+		"items[?(@.title)][0].tags[-]": {`items[0].tags[1] = "y"`},
+		"..tags[1:][]":                 {`items[2].tags[0] = "z"`},
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		matches, err := QueryAll(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		got := []string{}
+		for _, m := range matches {
+			got = append(got, fmt.Sprintf("%s = %s", m.Path, m.Value))
+
+			// The concrete path resolves to the same value, nulls of aligned iterators stand for missing values
+			res, err := m.Path.Query(data)
+			if string(m.Value) == "null" && errors.Is(err, ErrCantFindField) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %s", m.Path, err)
+			}
+			assert.Equals(string(res), string(m.Value))
+		}
+
+		if len(expected) == 0 {
+			expected = []string{}
+		}
+		assert.Equals(got, expected, fmt.Sprintf("%s: %v is not %v", tag, got, expected))
+	}
+
+	_, err := QueryAll(data, "missing.path")
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+
+	_, err = QueryAll(data, "items[][]")
+	assert.Assert(errors.Is(err, ErrNotAnArray), fmt.Sprintf("expected ErrNotAnArray, got %v", err))
+
+	_, err = QueryAll(data, "items | length")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+}
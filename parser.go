@@ -12,53 +12,92 @@ import (
 	"fmt"
 )
 
-type query struct {
-	Tokens []token
-}
-
-type token struct {
-	Type    tokenType
-	Content interface{}
-}
-
-type tokenType int
-
-const (
-	literalToken tokenType = iota
-	arrayIndexToken
-	arrayLastToken
-	arrayIteratorToken
-)
-
-var parseResult query
-
-//line parser.y:30
+//line parser.y:10
 type yySymType struct {
-	yys    int
-	str    string
-	num    int
-	query  query
-	token  token
-	tokens []token
+	yys          int
+	str          string
+	num          int
+	path         *Path
+	segment      Segment
+	segments     []Segment
+	alternatives []Alternative
+	alternative  Alternative
+	functions    []Function
+	function     Function
+	bound        *int
+	float        float64
+	literal      any
+	op           FilterOp
+	filter       FilterExpr
+	operand      FilterOperand
+	flag         bool
 }
 
 const IDENTIFIER = 57346
-const NUMBER = 57347
-const DOT = 57348
-const LBRACKET = 57349
-const RBRACKET = 57350
-const MINUS = 57351
+const STRING = 57347
+const TRUE = 57348
+const FALSE = 57349
+const NULL = 57350
+const FUNCTION = 57351
+const NUMBER = 57352
+const FLOAT = 57353
+const DOT = 57354
+const DESCENT = 57355
+const LBRACKET = 57356
+const RBRACKET = 57357
+const MINUS = 57358
+const COLON = 57359
+const STAR = 57360
+const PIPE = 57361
+const QUESTION = 57362
+const LPAREN = 57363
+const RPAREN = 57364
+const AT = 57365
+const ROOT = 57366
+const EQ = 57367
+const NE = 57368
+const LT = 57369
+const LE = 57370
+const GT = 57371
+const GE = 57372
+const AND = 57373
+const OR = 57374
+const NOT = 57375
 
 var yyToknames = [...]string{
 	"$end",
 	"error",
 	"$unk",
 	"IDENTIFIER",
+	"STRING",
+	"TRUE",
+	"FALSE",
+	"NULL",
+	"FUNCTION",
 	"NUMBER",
+	"FLOAT",
 	"DOT",
+	"DESCENT",
 	"LBRACKET",
 	"RBRACKET",
 	"MINUS",
+	"COLON",
+	"STAR",
+	"PIPE",
+	"QUESTION",
+	"LPAREN",
+	"RPAREN",
+	"AT",
+	"ROOT",
+	"EQ",
+	"NE",
+	"LT",
+	"LE",
+	"GT",
+	"GE",
+	"AND",
+	"OR",
+	"NOT",
 }
 
 var yyStatenames = [...]string{}
@@ -67,16 +106,72 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line parser.y:83
+//line parser.y:277
+
+func init() {
+	// Lists the expected tokens in syntax errors
+	yyErrorVerbose = true
+}
+
+func parse(input string) (*Path, error) {
+	if isPointer(input) {
+		return pointerPath(input)
+	}
 
-func parse(input string) (query, error) {
-	parseResult = query{}
 	lexer := newLexer(input)
-	result := yyParse(lexer)
-	if result != 0 {
-		return query{}, fmt.Errorf("parse error")
+	if yyNewParser().Parse(lexer) != 0 {
+		if lexer.err != nil {
+			return nil, lexer.err
+		}
+		return nil, ErrMalformedSyntax
 	}
-	return parseResult, nil
+
+	for _, alternative := range lexer.result.Alternatives {
+		if err := checkSegments(alternative.Segments); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, function := range lexer.result.Functions {
+		if len(function.Args) != builtinFunctions[function.Name].Args {
+			return nil, fmt.Errorf("%w: %s expects %d arguments", ErrMalformedSyntax, function.Name, builtinFunctions[function.Name].Args)
+		}
+	}
+
+	return lexer.result, nil
+}
+
+// checkSegments rejects what the grammar cant, a slice with a step of zero would never move
+func checkSegments(segments []Segment) error {
+	for _, seg := range segments {
+		if seg.Kind == SliceSegment && seg.Slice.Step != nil && *seg.Slice.Step == 0 {
+			return fmt.Errorf("%w: slice step cant be zero", ErrMalformedSyntax)
+		}
+
+		if seg.Filter != nil {
+			if err := checkFilter(*seg.Filter); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkFilter(expr FilterExpr) error {
+	for _, child := range expr.Children {
+		if err := checkFilter(child); err != nil {
+			return err
+		}
+	}
+
+	for _, operand := range expr.Operands {
+		if err := checkSegments(operand.Segments); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 //line yacctab:1
@@ -84,42 +179,99 @@ var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 56,
+	22, 44,
+	31, 44,
+	32, 44,
+	-2, 51,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 14
+const yyLast = 133
 
 var yyAct = [...]int8{
-	10, 13, 12, 9, 11, 5, 7, 3, 4, 6,
-	2, 1, 0, 8,
+	24, 5, 8, 56, 9, 55, 32, 21, 83, 23,
+	52, 60, 64, 65, 66, 73, 61, 63, 87, 46,
+	45, 3, 62, 25, 37, 44, 99, 54, 38, 58,
+	59, 93, 77, 78, 79, 80, 81, 82, 71, 53,
+	73, 72, 36, 48, 20, 41, 90, 73, 72, 47,
+	40, 49, 14, 15, 16, 17, 18, 68, 43, 69,
+	42, 12, 13, 70, 74, 75, 11, 50, 84, 88,
+	39, 7, 6, 51, 85, 86, 89, 96, 12, 13,
+	95, 10, 94, 91, 92, 35, 98, 98, 97, 97,
+	60, 64, 65, 66, 26, 61, 63, 67, 100, 76,
+	101, 62, 31, 22, 12, 13, 57, 28, 58, 59,
+	4, 34, 27, 29, 19, 30, 2, 33, 14, 15,
+	16, 17, 18, 14, 15, 16, 17, 18, 1, 0,
+	0, 0, 11,
 }
 
 var yyPact = [...]int16{
-	4, -1000, -1, -1000, -1000, 4, -1000, -5, -1000, -1000,
-	-6, -7, -1000, -1000,
+	48, -32768, 25, -32768, 91, -32768, -32768, -32768, -32768, -32768,
+	-32768, 3, 119, 97, -32768, -32768, -32768, -32768, -32768, 76,
+	48, -32768, 114, -32768, -32768, -32768, -32768, 3, 55, 35,
+	45, 43, 8, -1, -32768, -2, -32768, -32768, -32768, -32768,
+	-32768, 34, 3, -32768, 57, 6, 92, -32768, -32768, 42,
+	-32768, 53, 16, 6, 6, 7, -32768, -32768, -32768, -32768,
+	-32768, -32768, 64, -32768, -32768, -32768, -32768, -4, 3, 57,
+	-32768, 31, 6, 6, -32768, 9, 85, -32768, -32768, -32768,
+	-32768, -32768, -32768, 65, 65, -32768, -32768, -32768, -32768, 11,
+	-32768, -16, -32768, -32768, -32768, -32768, 114, -32768, -32768, 3,
+	-32768, -32768,
 }
 
-var yyPgo = [...]int8{
-	0, 11, 10, 7, 9,
+var yyPgo = [...]uint8{
+	0, 128, 116, 21, 114, 111, 110, 8, 1, 4,
+	2, 81, 6, 0, 10, 5, 3, 106, 99,
 }
 
 var yyR1 = [...]int8{
-	0, 1, 2, 2, 2, 3, 4, 4, 4,
+	0, 1, 2, 2, 3, 4, 4, 5, 5, 6,
+	6, 6, 6, 6, 6, 6, 6, 8, 8, 10,
+	11, 11, 11, 11, 11, 9, 9, 9, 9, 9,
+	9, 9, 9, 9, 13, 13, 12, 12, 12, 14,
+	14, 14, 14, 14, 14, 18, 18, 18, 18, 18,
+	18, 15, 15, 16, 16, 7, 7, 7, 7, 17,
+	17, 17, 17, 17, 17, 17, 17,
 }
 
 var yyR2 = [...]int8{
-	0, 1, 1, 3, 2, 1, 2, 3, 3,
+	0, 2, 1, 3, 1, 0, 2, 1, 4, 1,
+	1, 1, 1, 1, 2, 3, 2, 1, 2, 2,
+	1, 1, 1, 1, 1, 3, 3, 3, 4, 4,
+	3, 6, 8, 6, 0, 1, 0, 1, 2, 3,
+	3, 2, 3, 3, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 2, 2, 0, 3, 2, 2, 1,
+	1, 2, 1, 2, 1, 1, 1,
 }
 
 var yyChk = [...]int16{
-	-1000, -1, -2, -3, 4, 6, -4, 7, -3, 8,
-	5, 9, 8, 8,
+	-32768, -1, -2, -3, -6, -8, 24, 23, -10, -9,
+	-11, 18, 13, 14, 4, 5, 6, 7, 8, -4,
+	19, -10, 12, -9, -13, 20, -11, 15, 10, 16,
+	18, 5, -12, 20, -5, 9, -3, -8, -13, 15,
+	15, 10, 15, 15, 17, 21, 21, 15, -13, -12,
+	10, 16, -14, 33, 21, -15, -16, -17, 23, 24,
+	5, 10, 16, 11, 6, 7, 8, 5, 15, 17,
+	10, 22, 32, 31, -14, -14, -18, 25, 26, 27,
+	28, 29, 30, -7, -7, 10, 11, 22, -13, -12,
+	15, -14, -14, 22, -15, -16, 12, -9, -10, 15,
+	-8, -13,
 }
 
 var yyDef = [...]int8{
-	0, -2, 1, 2, 5, 0, 4, 0, 3, 6,
-	0, 0, 7, 8,
+	0, -2, 5, 2, 4, 9, 10, 11, 12, 13,
+	17, 34, 0, 36, 20, 21, 22, 23, 24, 1,
+	0, 14, 0, 16, 18, 35, 19, 34, 37, 0,
+	0, 0, 0, 0, 6, 7, 3, 15, 25, 26,
+	27, 38, 34, 30, 36, 0, 0, 28, 29, 0,
+	37, 0, 0, 0, 0, 0, -2, 52, 55, 55,
+	59, 60, 0, 62, 64, 65, 66, 0, 34, 36,
+	38, 0, 0, 0, 41, 0, 0, 45, 46, 47,
+	48, 49, 50, 53, 54, 61, 63, 8, 31, 0,
+	33, 39, 40, 42, 43, 51, 0, 57, 58, 34,
+	56, 32,
 }
 
 var yyTok1 = [...]int8{
@@ -127,7 +279,10 @@ var yyTok1 = [...]int8{
 }
 
 var yyTok2 = [...]int8{
-	2, 3, 4, 5, 6, 7, 8, 9,
+	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
+	32, 33,
 }
 
 var yyTok3 = [...]int8{
@@ -173,7 +328,7 @@ func yyNewParser() yyParser {
 	return &yyParserImpl{}
 }
 
-const yyFlag = -1000
+const yyFlag = -32768
 
 func yyTokname(c int) string {
 	if c >= 1 && c-1 < len(yyToknames) {
@@ -472,53 +627,375 @@ yydefault:
 	switch yynt {
 
 	case 1:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:51
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:59
 		{
-			parseResult = query{Tokens: yyDollar[1].tokens}
-			yyVAL.query = parseResult
+			// The result is stored on the lexer instead of a global so parsing is safe for concurrent use
+			yyVAL.path = &Path{Alternatives: yyDollar[1].alternatives, Functions: yyDollar[2].functions}
+			yylex.(*lexer).result = yyVAL.path
 		}
 	case 2:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:57
+//line parser.y:66
 		{
-			yyVAL.tokens = []token{yyDollar[1].token}
+			yyVAL.alternatives = []Alternative{yyDollar[1].alternative}
 		}
 	case 3:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:60
+//line parser.y:69
 		{
-			yyVAL.tokens = append(yyDollar[1].tokens, yyDollar[3].token)
+			yyVAL.alternatives = append(yyDollar[1].alternatives, yyDollar[3].alternative)
 		}
 	case 4:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:63
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:74
 		{
-			yyVAL.tokens = append(yyDollar[1].tokens, yyDollar[2].token)
+			yyVAL.alternative = Alternative{Segments: yyDollar[1].segments}
 		}
 	case 5:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:68
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line parser.y:79
 		{
-			yyVAL.token = token{Type: literalToken, Content: yyDollar[1].str}
+			yyVAL.functions = nil
 		}
 	case 6:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:73
+//line parser.y:82
 		{
-			yyVAL.token = token{Type: arrayIteratorToken}
+			yyVAL.functions = append(yyDollar[1].functions, yyDollar[2].function)
 		}
 	case 7:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:76
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:87
 		{
-			yyVAL.token = token{Type: arrayIndexToken, Content: yyDollar[2].num}
+			yyVAL.function = Function{Name: yyDollar[1].str}
 		}
 	case 8:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.y:90
+		{
+			yyVAL.function = Function{Name: yyDollar[1].str, Args: []string{yyDollar[3].str}}
+		}
+	case 9:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:95
+		{
+			yyVAL.segments = []Segment{yyDollar[1].segment}
+		}
+	case 10:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:98
+		{
+			yyVAL.segments = []Segment{{Kind: RootSegment}}
+		}
+	case 11:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:101
+		{
+			yyVAL.segments = []Segment{{Kind: CurrentSegment}}
+		}
+	case 12:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:104
+		{
+			yyVAL.segments = []Segment{yyDollar[1].segment}
+		}
+	case 13:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:107
+		{
+			yyVAL.segments = []Segment{yyDollar[1].segment}
+		}
+	case 14:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:110
+		{
+			yyVAL.segments = append(yyDollar[1].segments, yyDollar[2].segment)
+		}
+	case 15:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:79
+//line parser.y:113
+		{
+			yyVAL.segments = append(yyDollar[1].segments, yyDollar[3].segment)
+		}
+	case 16:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:116
+		{
+			yyVAL.segments = append(yyDollar[1].segments, yyDollar[2].segment)
+		}
+	case 17:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:121
+		{
+			yyVAL.segment = Segment{Kind: KeySegment, Key: yyDollar[1].str}
+		}
+	case 18:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:124
+		{
+			yyVAL.segment = Segment{Kind: WildcardSegment, Aligned: yyDollar[2].flag}
+		}
+	case 19:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:129
+		{
+			yyVAL.segment = Segment{Kind: DescentSegment, Key: yyDollar[2].str}
+		}
+	case 25:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:141
+		{
+			yyVAL.segment = Segment{Kind: IteratorSegment, Aligned: yyDollar[3].flag}
+		}
+	case 26:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:144
+		{
+			yyVAL.segment = Segment{Kind: IndexSegment, Index: yyDollar[2].num}
+		}
+	case 27:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:147
+		{
+			yyVAL.segment = Segment{Kind: LastSegment}
+		}
+	case 28:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.y:150
+		{
+			yyVAL.segment = Segment{Kind: IndexSegment, Index: -yyDollar[3].num}
+		}
+	case 29:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.y:153
+		{
+			yyVAL.segment = Segment{Kind: WildcardSegment, Aligned: yyDollar[4].flag}
+		}
+	case 30:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:156
+		{
+			yyVAL.segment = Segment{Kind: KeySegment, Key: yyDollar[2].str}
+		}
+	case 31:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line parser.y:159
+		{
+			yyVAL.segment = Segment{Kind: SliceSegment, Slice: SliceRange{Start: yyDollar[2].bound, End: yyDollar[4].bound}, Aligned: yyDollar[6].flag}
+		}
+	case 32:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line parser.y:162
+		{
+			yyVAL.segment = Segment{Kind: SliceSegment, Slice: SliceRange{Start: yyDollar[2].bound, End: yyDollar[4].bound, Step: yyDollar[6].bound}, Aligned: yyDollar[8].flag}
+		}
+	case 33:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line parser.y:165
+		{
+			filter := yyDollar[4].filter
+			yyVAL.segment = Segment{Kind: FilterSegment, Filter: &filter}
+		}
+	case 34:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line parser.y:172
+		{
+			yyVAL.flag = false
+		}
+	case 35:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:175
+		{
+			yyVAL.flag = true
+		}
+	case 36:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line parser.y:180
+		{
+			yyVAL.bound = nil
+		}
+	case 37:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:183
+		{
+			n := yyDollar[1].num
+			yyVAL.bound = &n
+		}
+	case 38:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:187
+		{
+			n := -yyDollar[2].num
+			yyVAL.bound = &n
+		}
+	case 39:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:193
+		{
+			yyVAL.filter = FilterExpr{Op: FilterOr, Children: []FilterExpr{yyDollar[1].filter, yyDollar[3].filter}}
+		}
+	case 40:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:196
+		{
+			yyVAL.filter = FilterExpr{Op: FilterAnd, Children: []FilterExpr{yyDollar[1].filter, yyDollar[3].filter}}
+		}
+	case 41:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:199
+		{
+			yyVAL.filter = FilterExpr{Op: FilterNot, Children: []FilterExpr{yyDollar[2].filter}}
+		}
+	case 42:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:202
+		{
+			yyVAL.filter = yyDollar[2].filter
+		}
+	case 43:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:205
+		{
+			yyVAL.filter = FilterExpr{Op: yyDollar[2].op, Operands: []FilterOperand{yyDollar[1].operand, yyDollar[3].operand}}
+		}
+	case 44:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:208
+		{
+			yyVAL.filter = FilterExpr{Op: FilterExists, Operands: []FilterOperand{yyDollar[1].operand}}
+		}
+	case 45:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:213
+		{
+			yyVAL.op = FilterEq
+		}
+	case 46:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:214
+		{
+			yyVAL.op = FilterNe
+		}
+	case 47:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:215
+		{
+			yyVAL.op = FilterLt
+		}
+	case 48:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:216
+		{
+			yyVAL.op = FilterLe
+		}
+	case 49:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:217
+		{
+			yyVAL.op = FilterGt
+		}
+	case 50:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:218
+		{
+			yyVAL.op = FilterGe
+		}
+	case 51:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:221
+		{
+			yyVAL.operand = yyDollar[1].operand
+		}
+	case 52:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:224
+		{
+			yyVAL.operand = FilterOperand{Literal: yyDollar[1].literal}
+		}
+	case 53:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:229
+		{
+			yyVAL.operand = FilterOperand{Current: true, Segments: yyDollar[2].segments}
+		}
+	case 54:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:232
+		{
+			yyVAL.operand = FilterOperand{Root: true, Segments: yyDollar[2].segments}
+		}
+	case 55:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line parser.y:237
+		{
+			yyVAL.segments = nil
+		}
+	case 56:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.y:240
+		{
+			yyVAL.segments = append(yyDollar[1].segments, yyDollar[3].segment)
+		}
+	case 57:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:243
+		{
+			yyVAL.segments = append(yyDollar[1].segments, yyDollar[2].segment)
+		}
+	case 58:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:246
+		{
+			yyVAL.segments = append(yyDollar[1].segments, yyDollar[2].segment)
+		}
+	case 59:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:252
+		{
+			yyVAL.literal = yyDollar[1].str
+		}
+	case 60:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:255
+		{
+			yyVAL.literal = float64(yyDollar[1].num)
+		}
+	case 61:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:258
+		{
+			yyVAL.literal = float64(-yyDollar[2].num)
+		}
+	case 62:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:261
+		{
+			yyVAL.literal = yyDollar[1].float
+		}
+	case 63:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.y:264
+		{
+			yyVAL.literal = -yyDollar[2].float
+		}
+	case 64:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:267
+		{
+			yyVAL.literal = true
+		}
+	case 65:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:270
+		{
+			yyVAL.literal = false
+		}
+	case 66:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.y:273
 		{
-			yyVAL.token = token{Type: arrayLastToken}
+			yyVAL.literal = nil
 		}
 	}
 	goto yystack /* stack new state and value */
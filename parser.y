@@ -5,57 +5,110 @@ package rjson
 import (
     "fmt"
 )
-
-type query struct {
-    Tokens []token
-}
-
-type token struct {
-    Type    tokenType
-    Content interface{}
-}
-
-type tokenType int
-
-const (
-    literalToken tokenType = iota
-    arrayIndexToken
-    arrayLastToken
-    arrayIteratorToken
-)
-
-var parseResult query
 %}
 
 %union {
     str string
     num int
-    query query
-    token token
-    tokens []token
+    path *Path
+    segment Segment
+    segments []Segment
+    alternatives []Alternative
+    alternative Alternative
+    functions []Function
+    function Function
+    bound *int
+    float float64
+    literal any
+    op FilterOp
+    filter FilterExpr
+    operand FilterOperand
+    flag bool
 }
 
-%token <str> IDENTIFIER
+%token <str> IDENTIFIER STRING TRUE FALSE NULL FUNCTION
 %token <num> NUMBER
-%token DOT LBRACKET RBRACKET MINUS
+%token <float> FLOAT
+%token DOT DESCENT LBRACKET RBRACKET MINUS COLON STAR PIPE
+%token QUESTION LPAREN RPAREN AT ROOT EQ NE LT LE GT GE AND OR NOT
+
+%type <path> query
+%type <alternatives> alternatives
+%type <alternative> alternative
+%type <functions> functions
+%type <function> function
+%type <segments> path_elements relative_elements
+%type <segment> path_element array_access descent
+%type <str> key
+%type <bound> slice_bound
+%type <flag> aligned
+%type <filter> filter_expr
+%type <operand> filter_operand current_path
+%type <literal> filter_literal
+%type <op> comparator
 
-%type <query> query
-%type <tokens> path_elements
-%type <token> path_element array_access
+%left OR
+%left AND
+%right NOT
 
 %start query
 
 %%
 
 query:
+    alternatives functions {
+        // The result is stored on the lexer instead of a global so parsing is safe for concurrent use
+        $$ = &Path{Alternatives: $1, Functions: $2}
+        yylex.(*lexer).result = $$
+    }
+
+alternatives:
+    alternative {
+        $$ = []Alternative{$1}
+    }
+|   alternatives PIPE alternative {
+        $$ = append($1, $3)
+    }
+
+alternative:
     path_elements {
-        parseResult = query{Tokens: $1}
-        $$ = parseResult
+        $$ = Alternative{Segments: $1}
+    }
+
+functions:
+    /* empty */ {
+        $$ = nil
+    }
+|   functions function {
+        $$ = append($1, $2)
+    }
+
+function:
+    FUNCTION {
+        $$ = Function{Name: $1}
+    }
+|   FUNCTION LPAREN STRING RPAREN {
+        $$ = Function{Name: $1, Args: []string{$3}}
     }
 
 path_elements:
     path_element {
-        $$ = []token{$1}
+        $$ = []Segment{$1}
+    }
+|   ROOT {
+        $$ = []Segment{{Kind: RootSegment}}
+    }
+|   AT {
+        $$ = []Segment{{Kind: CurrentSegment}}
+    }
+|   descent {
+        $$ = []Segment{$1}
+    }
+|   array_access {
+        $$ = []Segment{$1}
+    }
+|   path_elements descent {
+        $$ = append($1, $2)
     }
 |   path_elements DOT path_element {
         $$ = append($1, $3)
@@ -65,29 +118,226 @@ path_elements:
     }
 
 path_element:
-    IDENTIFIER {
-        $$ = token{Type: literalToken, Content: $1}
+    key {
+        $$ = Segment{Kind: KeySegment, Key: $1}
+    }
+|   STAR aligned {
+        $$ = Segment{Kind: WildcardSegment, Aligned: $2}
+    }
+
+descent:
+    DESCENT key {
+        $$ = Segment{Kind: DescentSegment, Key: $2}
     }
 
+key:
+    IDENTIFIER
+|   STRING
+|   TRUE
+|   FALSE
+|   NULL
+
 array_access:
-    LBRACKET RBRACKET {
-        $$ = token{Type: arrayIteratorToken}
+    LBRACKET RBRACKET aligned {
+        $$ = Segment{Kind: IteratorSegment, Aligned: $3}
     }
 |   LBRACKET NUMBER RBRACKET {
-        $$ = token{Type: arrayIndexToken, Content: $2}
+        $$ = Segment{Kind: IndexSegment, Index: $2}
     }
 |   LBRACKET MINUS RBRACKET {
-        $$ = token{Type: arrayLastToken}
+        $$ = Segment{Kind: LastSegment}
+    }
+|   LBRACKET MINUS NUMBER RBRACKET {
+        $$ = Segment{Kind: IndexSegment, Index: -$3}
+    }
+|   LBRACKET STAR RBRACKET aligned {
+        $$ = Segment{Kind: WildcardSegment, Aligned: $4}
+    }
+|   LBRACKET STRING RBRACKET {
+        $$ = Segment{Kind: KeySegment, Key: $2}
+    }
+|   LBRACKET slice_bound COLON slice_bound RBRACKET aligned {
+        $$ = Segment{Kind: SliceSegment, Slice: SliceRange{Start: $2, End: $4}, Aligned: $6}
+    }
+|   LBRACKET slice_bound COLON slice_bound COLON slice_bound RBRACKET aligned {
+        $$ = Segment{Kind: SliceSegment, Slice: SliceRange{Start: $2, End: $4, Step: $6}, Aligned: $8}
+    }
+|   LBRACKET QUESTION LPAREN filter_expr RPAREN RBRACKET {
+        filter := $4
+        $$ = Segment{Kind: FilterSegment, Filter: &filter}
+    }
+
+// A trailing ? keeps the elements the rest of the path doesnt resolve for as null
+aligned:
+    /* empty */ {
+        $$ = false
+    }
+|   QUESTION {
+        $$ = true
+    }
+
+slice_bound:
+    /* empty */ {
+        $$ = nil
+    }
+|   NUMBER {
+        n := $1
+        $$ = &n
+    }
+|   MINUS NUMBER {
+        n := -$2
+        $$ = &n
+    }
+
+filter_expr:
+    filter_expr OR filter_expr {
+        $$ = FilterExpr{Op: FilterOr, Children: []FilterExpr{$1, $3}}
+    }
+|   filter_expr AND filter_expr {
+        $$ = FilterExpr{Op: FilterAnd, Children: []FilterExpr{$1, $3}}
+    }
+|   NOT filter_expr {
+        $$ = FilterExpr{Op: FilterNot, Children: []FilterExpr{$2}}
+    }
+|   LPAREN filter_expr RPAREN {
+        $$ = $2
+    }
+|   filter_operand comparator filter_operand {
+        $$ = FilterExpr{Op: $2, Operands: []FilterOperand{$1, $3}}
+    }
+|   current_path {
+        $$ = FilterExpr{Op: FilterExists, Operands: []FilterOperand{$1}}
+    }
+
+comparator:
+    EQ { $$ = FilterEq }
+|   NE { $$ = FilterNe }
+|   LT { $$ = FilterLt }
+|   LE { $$ = FilterLe }
+|   GT { $$ = FilterGt }
+|   GE { $$ = FilterGe }
+
+filter_operand:
+    current_path {
+        $$ = $1
+    }
+|   filter_literal {
+        $$ = FilterOperand{Literal: $1}
+    }
+
+current_path:
+    AT relative_elements {
+        $$ = FilterOperand{Current: true, Segments: $2}
+    }
+|   ROOT relative_elements {
+        $$ = FilterOperand{Root: true, Segments: $2}
+    }
+
+relative_elements:
+    /* empty */ {
+        $$ = nil
+    }
+|   relative_elements DOT path_element {
+        $$ = append($1, $3)
+    }
+|   relative_elements array_access {
+        $$ = append($1, $2)
+    }
+|   relative_elements descent {
+        $$ = append($1, $2)
+    }
+
+// Literals are stored the same way json.Unmarshal would decode them into an any
+filter_literal:
+    STRING {
+        $$ = $1
+    }
+|   NUMBER {
+        $$ = float64($1)
+    }
+|   MINUS NUMBER {
+        $$ = float64(-$2)
+    }
+|   FLOAT {
+        $$ = $1
+    }
+|   MINUS FLOAT {
+        $$ = -$2
+    }
+|   TRUE {
+        $$ = true
+    }
+|   FALSE {
+        $$ = false
+    }
+|   NULL {
+        $$ = nil
     }
 
 %%
 
-func parse(input string) (query, error) {
-    parseResult = query{}
+func init() {
+    // Lists the expected tokens in syntax errors
+    yyErrorVerbose = true
+}
+
+func parse(input string) (*Path, error) {
+    if isPointer(input) {
+        return pointerPath(input)
+    }
+
     lexer := newLexer(input)
-    result := yyParse(lexer)
-    if result != 0 {
-        return query{}, fmt.Errorf("parse error")
+    if yyNewParser().Parse(lexer) != 0 {
+        if lexer.err != nil {
+            return nil, lexer.err
+        }
+        return nil, ErrMalformedSyntax
+    }
+
+    for _, alternative := range lexer.result.Alternatives {
+        if err := checkSegments(alternative.Segments); err != nil {
+            return nil, err
+        }
+    }
+
+    for _, function := range lexer.result.Functions {
+        if len(function.Args) != builtinFunctions[function.Name].Args {
+            return nil, fmt.Errorf("%w: %s expects %d arguments", ErrMalformedSyntax, function.Name, builtinFunctions[function.Name].Args)
+        }
     }
-    return parseResult, nil
+
+    return lexer.result, nil
+}
+
+// checkSegments rejects what the grammar cant, a slice with a step of zero would never move
+func checkSegments(segments []Segment) error {
+    for _, seg := range segments {
+        if seg.Kind == SliceSegment && seg.Slice.Step != nil && *seg.Slice.Step == 0 {
+            return fmt.Errorf("%w: slice step cant be zero", ErrMalformedSyntax)
+        }
+
+        if seg.Filter != nil {
+            if err := checkFilter(*seg.Filter); err != nil {
+                return err
+            }
+        }
+    }
+
+    return nil
+}
+
+func checkFilter(expr FilterExpr) error {
+    for _, child := range expr.Children {
+        if err := checkFilter(child); err != nil {
+            return err
+        }
+    }
+
+    for _, operand := range expr.Operands {
+        if err := checkSegments(operand.Segments); err != nil {
+            return err
+        }
+    }
+
+    return nil
 }
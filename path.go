@@ -0,0 +1,158 @@
+package rjson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+// Path is a parsed path, it holds no state between queries so its safe for concurrent use as long as its not modified
+type Path struct {
+	Alternatives []Alternative
+	Functions    []Function // Piped into the result of whichever alternative resolves
+}
+
+// Compile parses a path so it can be reused for many queries
+func Compile(path string) (*Path, error) {
+	return parse(path)
+}
+
+// MustCompile is like Compile but panics if the path cant be parsed, meant for paths known at init
+func MustCompile(path string) *Path {
+	p, err := Compile(path)
+	if err != nil {
+		panic(fmt.Sprintf("rjson: Compile(%q): %s", path, err))
+	}
+
+	return p
+}
+
+// isPointerOnly reports if the path came from a json pointer and can be written back as one
+func (p *Path) isPointerOnly() bool {
+	if len(p.Alternatives) != 1 || len(p.Functions) > 0 || len(p.Alternatives[0].Segments) == 0 {
+		return false
+	}
+
+	for _, seg := range p.Alternatives[0].Segments {
+		if seg.Kind != PointerSegment {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the path in its canonical form, parsing it again gives back the same path
+func (p *Path) String() string {
+	if p.isPointerOnly() {
+		pointer, _ := p.Pointer()
+		return pointer
+	}
+
+	alternatives := make([]string, len(p.Alternatives))
+	for i, alternative := range p.Alternatives {
+		alternatives[i] = alternative.String()
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(alternatives, " "+string(AlternativeSeparator)+" "))
+	writeFunctions(&b, p.Functions)
+
+	return b.String()
+}
+
+// Pointer returns the path as an RFC 6901 json pointer, only paths made of keys and non negative indexes can be converted
+func (p *Path) Pointer() (string, error) {
+	if len(p.Alternatives) != 1 || len(p.Functions) > 0 {
+		return "", fmt.Errorf("%w: alternatives and functions cant be represented as a json pointer", ErrMalformedSyntax)
+	}
+
+	var b strings.Builder
+	if err := writePointer(&b, p.Alternatives[0].Segments); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// Query runs the path against json data, same as QueryJson
+func (p *Path) Query(data []byte) (json.RawMessage, error) {
+	e, err := newEvaluator(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.query(e, data)
+}
+
+// query runs the path against data, which is part of the document e was made for
+func (p *Path) query(e *evaluator, data []byte) (object json.RawMessage, err error) {
+	// Nothing is decoded up front, the evaluator only looks at the parts of the document the path goes through
+	if object = bytes.TrimSpace(data); len(object) == 0 {
+		return nil, fmt.Errorf("%w: empty document", ErrInvalidJson)
+	}
+
+	var res json.RawMessage
+	err = p.eachAlternative(func(alternative Alternative) error {
+		m, err := e.run(object, alternative.Segments)
+		res = m.value
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Functions apply to whichever alternative resolved
+	for _, function := range p.Functions {
+		if res, err = applyFunction(res, function); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// eachAlternative calls fn with the alternatives in order until one resolves, the ones that dont exist in the
+// document or expect another shape move on to the next one. The error of the last alternative is returned as a *QueryError if none resolve
+func (p *Path) eachAlternative(fn func(alternative Alternative) error) (err error) {
+	for i, alternative := range p.Alternatives {
+		if err = fn(alternative); isUnresolved(err) {
+			err = queryError(alternative, err)
+			if Debug && i < len(p.Alternatives)-1 {
+				fmt.Printf("Alternative %d of tag '%s' didnt resolve: %s\n", i+1, p, err)
+			}
+			continue
+		} else if err != nil {
+			return queryError(alternative, err)
+		}
+
+		if Debug && len(p.Alternatives) > 1 {
+			fmt.Printf("Tag '%s' matched alternative %d\n", p, i+1)
+		}
+
+		return nil
+	}
+
+	return
+}
+
+// tagCache holds the compiled struct tags, their amount is bounded by the types passed to Unmarshal
+var tagCache sync.Map
+
+// compileTag compiles a struct tag once and reuses it for later calls
+func compileTag(tag string) (*Path, error) {
+	if p, ok := tagCache.Load(tag); ok {
+		return p.(*Path), nil
+	}
+
+	p, err := Compile(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	tagCache.Store(tag, p)
+	return p, nil
+}
@@ -0,0 +1,62 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+var namesPath = MustCompile("items[].name | names")
+
+func TestCompile(t *testing.T) {
+	assert.TestState = t
+
+	_, err := Compile("items[")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	// A zero step is rejected before the path runs, also inside of filters
+	for _, tag := range []string{"items[::0]", "a | items[1:3:0].name", "items[?(@.tags[::0])]"} {
+		_, err = Compile(tag)
+		assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("%s: expected ErrMalformedSyntax, got %v", tag, err))
+	}
+
+	defer func() {
+		assert.Assert(recover() != nil, "MustCompile should panic on malformed paths")
+	}()
+	MustCompile("items[")
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			data := fmt.Appendf(nil, `{"items": [{"name": "a%d"}, {"name": "b%d"}], "n": %d}`, i, i, i)
+			expected := fmt.Sprintf(`["a%d","b%d"]`, i, i)
+
+			if res, err := namesPath.Query(data); err != nil || string(res) != expected {
+				t.Errorf("Path.Query: got %s %v, expected %s", res, err, expected)
+			}
+
+			if res, err := QueryJson(data, fmt.Sprintf("items[%d] | n", i%3)); err != nil {
+				t.Errorf("QueryJson: %s", err)
+			} else if i%3 == 2 && string(res) != fmt.Sprint(i) {
+				t.Errorf("QueryJson: got %s, expected %d", res, i)
+			}
+
+			var out struct {
+				Names []string `rjson:"items[].name"`
+			}
+			if err := Unmarshal(data, &out); err != nil || len(out.Names) != 2 {
+				t.Errorf("Unmarshal: got %v %v", out.Names, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
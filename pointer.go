@@ -0,0 +1,185 @@
+package rjson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-json"
+)
+
+const PointerSeparator = '/' // Paths starting with it are parsed as RFC 6901 json pointers, e.g /items/0/name
+
+// isPointer reports if the path should be parsed as a json pointer
+func isPointer(path string) bool {
+	return strings.HasPrefix(path, string(PointerSeparator))
+}
+
+// parsePointer splits a json pointer into its unescaped reference tokens
+func parsePointer(pointer string) (segments []string, err error) {
+	if !isPointer(pointer) {
+		return nil, fmt.Errorf("%w: json pointer has to start with %c", ErrMalformedSyntax, PointerSeparator)
+	}
+
+	for _, segment := range strings.Split(pointer[1:], string(PointerSeparator)) {
+		// ~ is only valid as the start of ~0 or ~1
+		for i := 0; i < len(segment); i++ {
+			if segment[i] == '~' && (i+1 >= len(segment) || (segment[i+1] != '0' && segment[i+1] != '1')) {
+				return nil, fmt.Errorf("%w: invalid escape in json pointer segment %q", ErrMalformedSyntax, segment)
+			}
+		}
+
+		segments = append(segments, strings.NewReplacer("~1", "/", "~0", "~").Replace(segment))
+	}
+
+	return
+}
+
+// pointerPath turns a json pointer into a path, segments are resolved as keys or indexes depending on the document
+func pointerPath(pointer string) (*Path, error) {
+	segments, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	path := make([]Segment, len(segments))
+	for i, segment := range segments {
+		path[i] = Segment{Kind: PointerSegment, Key: segment}
+	}
+
+	return &Path{Alternatives: []Alternative{{Segments: path}}}, nil
+}
+
+// pointerIndex parses an array index the way RFC 6901 allows it, digits without leading zeros
+func pointerIndex(segment string) (int, bool) {
+	if segment == "" || (len(segment) > 1 && segment[0] == '0') {
+		return 0, false
+	}
+
+	for _, r := range segment {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+
+	i, err := strconv.Atoi(segment)
+	return i, err == nil
+}
+
+// resolvePointerSegment decides if a pointer segment is an index or a key based on the value its applied to
+func resolvePointerSegment(object json.RawMessage, key string) (Segment, error) {
+	if jsonKind(object) != "array" {
+		return Segment{Kind: KeySegment, Key: key}, nil
+	}
+
+	i, ok := pointerIndex(key)
+	if !ok {
+		return Segment{}, fmt.Errorf("%w %q", ErrInvalidIndex, key)
+	}
+
+	return Segment{Kind: IndexSegment, Index: i}, nil
+}
+
+// isIdentifier reports if key can be written in a path without quoting it
+func isIdentifier(key string) bool {
+	for i, r := range key {
+		if !unicode.IsLetter(r) && (i == 0 || (!unicode.IsDigit(r) && r != '_')) {
+			return false
+		}
+	}
+
+	return key != ""
+}
+
+// quoteKey quotes key if it cant be written in a path as is, keys named like functions are quoted so they cant be mistaken for one
+func quoteKey(key string) string {
+	if _, function := builtinFunctions[key]; isIdentifier(key) && !function {
+		return key
+	}
+
+	bs, _ := json.Marshal(key)
+	return string(bs)
+}
+
+// PointerToPath converts an RFC 6901 json pointer into the dotted path syntax, e.g /items/0/name becomes items[0].name.
+// A segment like /1 can be a key or an index, so they are told apart by the values of data the pointer leads to.
+// Segments past what data has, or all of them when data is empty, become indexes when they are digits and keys otherwise,
+// so pointers to values that dont exist yet can be converted too, e.g the target of a json patch add
+func PointerToPath(data []byte, pointer string) (string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return "", err
+	}
+
+	var value json.RawMessage // What the segments so far lead to, nil once they leave data
+	if len(bytes.TrimSpace(data)) > 0 {
+		e, err := newEvaluator(data)
+		if err != nil {
+			return "", err
+		}
+
+		value = e.root
+	}
+
+	segments := make([]Segment, len(tokens))
+	for i, token := range tokens {
+		switch jsonKind(value) {
+		case "array":
+			index, ok := pointerIndex(token)
+			if token == "-" {
+				// The element after the last one, only json patch can refer to it
+				segments[i], value = keyStep(token), nil
+				continue
+			} else if !ok {
+				return "", fmt.Errorf("%w %q, %s is an array", ErrInvalidIndex, token, Alternative{Segments: segments[:i]})
+			}
+
+			segments[i] = indexStep(index)
+			value, _, _ = arrayElement(value, index)
+		case "object":
+			segments[i] = keyStep(token)
+			value, _ = lookupKey(value, token)
+		default:
+			if index, ok := pointerIndex(token); ok {
+				segments[i] = indexStep(index)
+			} else {
+				segments[i] = keyStep(token)
+			}
+
+			value = nil
+		}
+	}
+
+	return Alternative{Segments: segments}.String(), nil
+}
+
+// PathToPointer converts a path in the dotted syntax into an RFC 6901 json pointer, e.g items[0].name becomes /items/0/name.
+// Only keys and non negative indexes can be represented as a json pointer.
+func PathToPointer(path string) (string, error) {
+	p, err := parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	return p.Pointer()
+}
+
+// writePointer writes the segments as a json pointer, only keys and non negative indexes can be represented
+func writePointer(b *strings.Builder, segments []Segment) error {
+	for _, seg := range segments {
+		b.WriteRune(PointerSeparator)
+
+		switch {
+		case seg.Kind == KeySegment || seg.Kind == PointerSegment:
+			b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(seg.Key))
+		case seg.Kind == IndexSegment && seg.Index >= 0:
+			b.WriteString(strconv.Itoa(seg.Index))
+		default:
+			return fmt.Errorf("%w: %s cant be represented as a json pointer", ErrMalformedSyntax, Alternative{Segments: segments})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+func TestPointer(t *testing.T) {
+	data := []byte(`{
+		"items": [{"name": "a"}, {"name": "b"}],
+		"jarray": [{"1": "uwu"}],
+		"a/b": {"m~n": 1},
+		"": 2
+	}`)
+
+	cases := map[string]string{
+		"/items/0/name": `"a"`,
+		"/items/1":      `{"name": "b"}`,
+		"/jarray/0/1":   `"uwu"`,
+		"/a~1b/m~0n":    `1`,
+		"/":             `2`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	var out struct {
+		Name string `rjson:"/items/1/name"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Name, "b")
+
+	_, err := QueryJson(data, "/a~2b")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	_, err = QueryJson(data, "/items/01")
+	assert.Assert(errors.Is(err, ErrInvalidIndex), fmt.Sprintf("expected ErrInvalidIndex, got %v", err))
+}
+
+func TestPointerConversion(t *testing.T) {
+	data := []byte(`{
+		"items": [{"name": "a"}],
+		"jarray": [[0, 1], {"1": "uwu"}],
+		"a/b": {"m~n": 1},
+		"content-type": {"01": 2},
+		"": 3
+	}`)
+
+	cases := map[string]string{
+		"/items/0/name":    `items[0].name`,
+		"/jarray/0/1":      `jarray[0][1]`,
+		"/jarray/1/1":      `jarray[1]."1"`,
+		"/a~1b/m~0n":       `"a/b"."m~n"`,
+		"/content-type/01": `"content-type"."01"`,
+		"/":                `""`,
+	}
+
+	assert.TestState = t
+	for pointer, path := range cases {
+		res, err := PointerToPath(data, pointer)
+		if err != nil {
+			t.Fatalf("%s: %s", pointer, err)
+		}
+		assert.Equals(res, path)
+
+		back, err := PathToPointer(path)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		assert.Equals(back, pointer)
+
+		// Both have to point at the same value
+		expected, _ := QueryJson(data, pointer)
+		got, err := QueryJson(data, path)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		assert.Equals(string(got), string(expected))
+	}
+
+	res, err := PointerToPath([]byte(`[{"name": "a"}]`), "/0/name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(res, `[0].name`)
+
+	_, err = PointerToPath(data, "/items/01")
+	assert.Assert(errors.Is(err, ErrInvalidIndex), fmt.Sprintf("expected ErrInvalidIndex, got %v", err))
+
+	// Segments data doesnt have are converted by how they look
+	unresolved := map[string]string{
+		"/missing/0":     `missing[0]`,
+		"/items/3/name":  `items[3].name`,
+		"/items/-":       `items."-"`,
+		"/jarray/1/2/x":  `jarray[1]."2".x`,
+		"/items/0/n/0/a": `items[0].n[0].a`,
+	}
+
+	for pointer, path := range unresolved {
+		res, err := PointerToPath(data, pointer)
+		if err != nil {
+			t.Fatalf("%s: %s", pointer, err)
+		}
+		assert.Equals(res, path)
+
+		back, err := PathToPointer(path)
+		assert.Equals(err, nil)
+		assert.Equals(back, pointer)
+	}
+
+	// Without a document every segment is
+	res, err = PointerToPath(nil, "/paths/~1users~1{id}/get/parameters/0")
+	assert.Equals(err, nil)
+	assert.Equals(res, `paths."/users/{id}".get.parameters[0]`)
+
+	_, err = PointerToPath(data, "/a~2b")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	for _, path := range []string{"items[]", "items[-1]", "items[-]", "a | b", "a | length", "..a"} {
+		_, err := PathToPointer(path)
+		assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("%s: expected ErrMalformedSyntax, got %v", path, err))
+	}
+}
@@ -0,0 +1,244 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/goccy/go-json"
+)
+
+var ErrWrongType = errors.New("wrong json type")
+
+// Type is the kind of json value a Result holds
+type Type int
+
+const (
+	TypeMissing Type = iota // The path didnt resolve
+	TypeNull
+	TypeBool
+	TypeNumber
+	TypeString
+	TypeArray
+	TypeObject
+)
+
+var typeNames = map[Type]string{
+	TypeMissing: "nothing",
+	TypeNull:    "null",
+	TypeBool:    "boolean",
+	TypeNumber:  "number",
+	TypeString:  "string",
+	TypeArray:   "array",
+	TypeObject:  "object",
+}
+
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+// Result is the value a path resolved to, its accessors report values of the wrong type with ErrWrongType instead of returning zero values
+type Result struct {
+	raw  json.RawMessage
+	root []byte // Document the value is from, so $ keeps working in chained calls
+	err  error  // Why the path didnt resolve
+}
+
+// Get runs the path against json data, e.g rjson.Get(data, "video.views").Int()
+func Get(data []byte, tag string) Result {
+	path, err := Compile(tag)
+	if err != nil {
+		return Result{err: fmt.Errorf("failed to parse tag '%s': %w", tag, err)}
+	}
+
+	return path.Get(data)
+}
+
+// Get runs the path against json data, same as the Get function
+func (p *Path) Get(data []byte) Result {
+	e, err := newEvaluator(data)
+	if err != nil {
+		return Result{err: err}
+	}
+
+	return p.get(e, data)
+}
+
+func (p *Path) get(e *evaluator, data []byte) Result {
+	raw, err := p.query(e, data)
+	return Result{raw: raw, root: e.root, err: err}
+}
+
+// Get runs a path relative to the value of the result, $ still refers to the root of the document
+func (r Result) Get(tag string) Result {
+	if err := r.Err(); err != nil {
+		return Result{err: err}
+	}
+
+	path, err := Compile(tag)
+	if err != nil {
+		return Result{err: fmt.Errorf("failed to parse tag '%s': %w", tag, err)}
+	}
+
+	return path.get(&evaluator{root: r.root}, r.raw)
+}
+
+// Exists reports if the path resolved to a value, json null included. The zero Result doesnt exist
+func (r Result) Exists() bool {
+	return r.raw != nil && r.err == nil
+}
+
+// Err returns why the path didnt resolve, nil if it did
+func (r Result) Err() error {
+	if r.err == nil && r.raw == nil {
+		return fmt.Errorf("%w: the result is empty", ErrCantFindField)
+	}
+
+	return r.err
+}
+
+// Raw returns the json of the value, nil if the path didnt resolve
+func (r Result) Raw() json.RawMessage {
+	return r.raw
+}
+
+// Type returns the kind of the value, TypeMissing if the path didnt resolve
+func (r Result) Type() Type {
+	if !r.Exists() {
+		return TypeMissing
+	}
+
+	switch jsonKind(r.raw) {
+	case "null":
+		return TypeNull
+	case "boolean":
+		return TypeBool
+	case "number":
+		return TypeNumber
+	case "string":
+		return TypeString
+	case "array":
+		return TypeArray
+	case "object":
+		return TypeObject
+	}
+
+	return TypeMissing
+}
+
+// expect returns an error if the value isnt of type t
+func (r Result) expect(t Type) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if found := r.Type(); found != t {
+		return fmt.Errorf("%w: expected %s, found %s", ErrWrongType, t, found)
+	}
+
+	return nil
+}
+
+// String returns strings without their quotes and other values as json, empty if the path didnt resolve, use Str to have wrong types reported
+func (r Result) String() string {
+	if !r.Exists() {
+		return ""
+	}
+
+	if s, err := r.Str(); err == nil {
+		return s
+	}
+
+	return string(r.raw)
+}
+
+// Str returns the value of a json string
+func (r Result) Str() (s string, err error) {
+	if err = r.expect(TypeString); err != nil {
+		return
+	}
+
+	err = json.Unmarshal(r.raw, &s)
+	return
+}
+
+// Int returns the value of a json number, numbers with a fraction or out of the int64 range are reported
+func (r Result) Int() (int64, error) {
+	if err := r.expect(TypeNumber); err != nil {
+		return 0, err
+	}
+
+	if i, err := strconv.ParseInt(string(r.raw), 10, 64); err == nil {
+		return i, nil
+	}
+
+	// Integers can still be written with an exponent, e.g 1e3
+	f, err := strconv.ParseFloat(string(r.raw), 64)
+	if err != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %s isnt an int64", ErrWrongType, r.raw)
+	}
+
+	return int64(f), nil
+}
+
+// Float returns the value of a json number
+func (r Result) Float() (float64, error) {
+	if err := r.expect(TypeNumber); err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(string(r.raw), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s isnt a valid number", ErrInvalidJson, r.raw)
+	}
+
+	return f, nil
+}
+
+// Bool returns the value of a json boolean
+func (r Result) Bool() (bool, error) {
+	if err := r.expect(TypeBool); err != nil {
+		return false, err
+	}
+
+	return string(r.raw) == "true", nil
+}
+
+// Array returns the elements of a json array
+func (r Result) Array() ([]Result, error) {
+	if err := r.expect(TypeArray); err != nil {
+		return nil, err
+	}
+
+	elements, err := decodeArray(r.raw)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(elements))
+	for i, element := range elements {
+		results[i] = Result{raw: element, root: r.root}
+	}
+
+	return results, nil
+}
+
+// Map returns the members of a json object, when a key is there more than once the last one is used
+func (r Result) Map() (map[string]Result, error) {
+	if err := r.expect(TypeObject); err != nil {
+		return nil, err
+	}
+
+	members, err := objectMembers(r.raw)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]Result, len(members))
+	for _, m := range members {
+		results[m.Key] = Result{raw: m.Value, root: r.root}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,96 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+func TestGet(t *testing.T) {
+	data := []byte(`{
+		"video": {"title": "a", "views": 1e3, "rating": 4.5, "live": false, "tags": ["x", "y"], "owner": null},
+		"base": "https://example.com"
+	}`)
+
+	assert.TestState = t
+
+	title, err := Get(data, "video.title").Str()
+	assert.Equals(err, nil)
+	assert.Equals(title, "a")
+
+	views, err := Get(data, "video.views").Int()
+	assert.Equals(err, nil)
+	assert.Equals(views, int64(1000))
+
+	rating, err := Get(data, "video.rating").Float()
+	assert.Equals(err, nil)
+	assert.Equals(rating, 4.5)
+
+	live, err := Get(data, "video.live").Bool()
+	assert.Equals(err, nil)
+	assert.Equals(live, false)
+
+	video := Get(data, "video")
+	assert.Equals(video.Type(), TypeObject)
+	assert.Equals(video.Get("owner").Type(), TypeNull)
+	assert.Assert(video.Get("owner").Exists(), "null values exist")
+
+	base, err := video.Get("$.base").Str()
+	assert.Equals(err, nil)
+	assert.Equals(base, "https://example.com")
+
+	tags, err := video.Get("tags").Array()
+	assert.Equals(err, nil)
+	assert.Equals(len(tags), 2)
+	second, _ := tags[1].Str()
+	assert.Equals(second, "y")
+
+	// String is a fmt.Stringer, strings lose their quotes and the rest stays json
+	assert.Equals(Get(data, "video.title").String(), "a")
+	assert.Equals(fmt.Sprint(Get(data, "video.views")), "1e3")
+	assert.Equals(Get(data, "video.tags").String(), `["x", "y"]`)
+	assert.Equals(Get(data, "video.owner").String(), "null")
+	assert.Equals(Get(data, "video.missing").String(), "")
+
+	members, err := video.Map()
+	assert.Equals(err, nil)
+	assert.Equals(len(members), 6)
+	assert.Equals(string(members["tags"].Raw()), `["x", "y"]`)
+
+	// Type mismatches are reported instead of giving zero values
+	mismatches := map[string]func() error{
+		"string as int":   func() error { _, err := Get(data, "video.title").Int(); return err },
+		"float as int":    func() error { _, err := Get(data, "video.rating").Int(); return err },
+		"number as bool":  func() error { _, err := Get(data, "video.views").Bool(); return err },
+		"null as string":  func() error { _, err := Get(data, "video.owner").Str(); return err },
+		"object as array": func() error { _, err := video.Array(); return err },
+		"array as map":    func() error { _, err := Get(data, "video.tags").Map(); return err },
+	}
+
+	for name, fn := range mismatches {
+		err := fn()
+		assert.Assert(errors.Is(err, ErrWrongType), fmt.Sprintf("%s: expected ErrWrongType, got %v", name, err))
+	}
+
+	missing := Get(data, "video.missing")
+	assert.Assert(!missing.Exists(), "missing values dont exist")
+	assert.Equals(missing.Type(), TypeMissing)
+	assert.Equals(missing.Raw() == nil, true)
+
+	_, err = missing.Get("deeper").Str()
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+
+	assert.Assert(errors.Is(Get(data, "video[").Err(), ErrMalformedSyntax), "expected ErrMalformedSyntax")
+
+	// The zero value is missing too
+	var zero Result
+	assert.Assert(!zero.Exists(), "the zero result doesnt exist")
+	assert.Equals(zero.Type(), TypeMissing)
+	assert.Equals(zero.String(), "")
+	assert.Assert(errors.Is(zero.Err(), ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", zero.Err()))
+
+	_, err = zero.Get("a").Int()
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+}
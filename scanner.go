@@ -0,0 +1,632 @@
+package rjson
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// The scanner walks the raw json bytes instead of decoding them, values are sliced out of the
+// document without copying and subtrees that arent needed are skipped over
+
+// skipSpace returns the index of the first non whitespace byte at or after i
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\n' || data[i] == '\r' || data[i] == '\t') {
+		i++
+	}
+
+	return i
+}
+
+// stringEnd returns the index right after the string starting at i
+func stringEnd(data []byte, i int) (int, error) {
+	for j := i + 1; j < len(data); j++ {
+		switch c := data[j]; {
+		case c == '\\':
+			if !validEscape(data, j) {
+				return 0, fmt.Errorf("%w: invalid escape in string at offset %d", ErrInvalidJson, j)
+			}
+			j++
+		case c == '"':
+			return j + 1, nil
+		case c < 0x20:
+			return 0, fmt.Errorf("%w: control character in string at offset %d", ErrInvalidJson, j)
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unterminated string at offset %d", ErrInvalidJson, i)
+}
+
+// validEscape reports if the backslash at i starts a valid escape, the hex digits of \u are checked here
+func validEscape(data []byte, i int) bool {
+	if i+1 >= len(data) {
+		return false
+	}
+
+	switch data[i+1] {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+		return true
+	case 'u':
+		if i+5 >= len(data) {
+			return false
+		}
+
+		for _, c := range data[i+2 : i+6] {
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// valueEnd returns the index right after the value starting at i, containers are skipped by
+// counting brackets so their contents are only looked at once
+func valueEnd(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, fmt.Errorf("%w: unexpected end of input", ErrInvalidJson)
+	}
+
+	switch data[i] {
+	case '"':
+		return stringEnd(data, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case '"':
+				end, err := stringEnd(data, j)
+				if err != nil {
+					return 0, err
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+
+		return 0, fmt.Errorf("%w: unterminated %s at offset %d", ErrInvalidJson, jsonKind(data[i:]), i)
+	case ',', ':', '}', ']':
+		return 0, fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidJson, data[i], i)
+	}
+
+	return scalarEnd(data, i)
+}
+
+// scalarEnd returns the index right after the number, true, false or null starting at i
+func scalarEnd(data []byte, i int) (int, error) {
+	var end int
+	var ok bool
+	switch data[i] {
+	case 't':
+		end, ok = i+4, bytes.HasPrefix(data[i:], []byte("true"))
+	case 'f':
+		end, ok = i+5, bytes.HasPrefix(data[i:], []byte("false"))
+	case 'n':
+		end, ok = i+4, bytes.HasPrefix(data[i:], []byte("null"))
+	default:
+		end, ok = numberEnd(data, i)
+	}
+
+	// The scalar has to run until the next delimiter, e.g tru or 1x arent values
+	if !ok || (end < len(data) && !isDelimiter(data[end])) {
+		return 0, fmt.Errorf("%w: invalid value at offset %d", ErrInvalidJson, i)
+	}
+
+	return end, nil
+}
+
+// validate checks that data holds exactly one json value, the parts of it the scanner skips over
+// later are only counted through so they have to be checked once up front
+func validate(data []byte) error {
+	var stack [64]byte
+	closers := stack[:0] // Closing brackets of the containers the value at i is in
+	i := skipSpace(data, 0)
+	for {
+		if i >= len(data) {
+			return fmt.Errorf("%w: unexpected end of input", ErrInvalidJson)
+		}
+
+		switch c := data[i]; c {
+		case '{', '[':
+			closer := byte(']')
+			if c == '{' {
+				closer = '}'
+			}
+
+			if i = skipSpace(data, i+1); i < len(data) && data[i] == closer {
+				i++
+				break
+			}
+
+			closers = append(closers, closer)
+			if c == '{' {
+				var err error
+				if i, err = memberStart(data, i); err != nil {
+					return err
+				}
+			}
+			continue
+		default:
+			end, err := valueEnd(data, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		}
+
+		// After a value either the container it is in goes on, ends or the document ends
+		for i = skipSpace(data, i); len(closers) > 0; i = skipSpace(data, i) {
+			if i >= len(data) {
+				return fmt.Errorf("%w: unexpected end of input", ErrInvalidJson)
+			}
+
+			closer := closers[len(closers)-1]
+			if data[i] == closer {
+				closers = closers[:len(closers)-1]
+				i++
+				continue
+			} else if data[i] != ',' {
+				return fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidJson, data[i], i)
+			}
+
+			i = skipSpace(data, i+1)
+			if closer == '}' {
+				var err error
+				if i, err = memberStart(data, i); err != nil {
+					return err
+				}
+			}
+			break
+		}
+
+		if len(closers) == 0 {
+			if i < len(data) {
+				return fmt.Errorf("%w: unexpected %q after the value at offset %d", ErrInvalidJson, data[i], i)
+			}
+
+			return nil
+		}
+	}
+}
+
+// memberStart moves past the key of the member starting at i, the returned index is where its value starts
+func memberStart(data []byte, i int) (int, error) {
+	if i >= len(data) || data[i] != '"' {
+		return 0, fmt.Errorf("%w: expected a key at offset %d", ErrInvalidJson, i)
+	}
+
+	end, err := stringEnd(data, i)
+	if err != nil {
+		return 0, err
+	}
+
+	if i = skipSpace(data, end); i >= len(data) || data[i] != ':' {
+		return 0, fmt.Errorf("%w: expected : at offset %d", ErrInvalidJson, i)
+	}
+
+	return skipSpace(data, i+1), nil
+}
+
+// numberEnd returns the index right after the number starting at i, ok is false if it doesnt follow the json number grammar
+func numberEnd(data []byte, i int) (end int, ok bool) {
+	digits := func(j int) int {
+		for j < len(data) && data[j] >= '0' && data[j] <= '9' {
+			j++
+		}
+		return j
+	}
+
+	j := i
+	if j < len(data) && data[j] == '-' {
+		j++
+	}
+
+	// No leading zeros, 0 is only followed by a fraction or exponent
+	switch {
+	case j < len(data) && data[j] == '0':
+		j++
+	case j < len(data) && data[j] >= '1' && data[j] <= '9':
+		j = digits(j)
+	default:
+		return j, false
+	}
+
+	if j < len(data) && data[j] == '.' {
+		if end := digits(j + 1); end > j+1 {
+			j = end
+		} else {
+			return end, false
+		}
+	}
+
+	if j < len(data) && (data[j] == 'e' || data[j] == 'E') {
+		j++
+		if j < len(data) && (data[j] == '+' || data[j] == '-') {
+			j++
+		}
+
+		if end := digits(j); end > j {
+			j = end
+		} else {
+			return end, false
+		}
+	}
+
+	return j, true
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\n', '\r', '\t', ',', ':', '{', '}', '[', ']', '"':
+		return true
+	}
+
+	return false
+}
+
+// nextItem moves past the separator after a member or element, close is the byte ending the container
+func nextItem(data []byte, i int, close byte) (int, error) {
+	if i = skipSpace(data, i); i < len(data) && data[i] == ',' {
+		return skipSpace(data, i+1), nil
+	} else if i < len(data) && data[i] != close {
+		return 0, fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidJson, data[i], i)
+	}
+
+	return i, nil
+}
+
+// eachMember calls fn with the raw quoted key and the value of every member of an object in document order,
+// iteration stops once fn returns false
+func eachMember(object []byte, fn func(key, value []byte) (bool, error)) error {
+	return eachMemberAt(object, func(key []byte, _, start, end int) (bool, error) {
+		return fn(key, object[start:end:end])
+	})
+}
+
+// eachMemberAt is eachMember for callers that need to know where the members are,
+// at is where the key starts and start and end are the bounds of the value in object
+func eachMemberAt(object []byte, fn func(key []byte, at, start, end int) (bool, error)) error {
+	i := skipSpace(object, 0)
+	if i >= len(object) || object[i] != '{' {
+		return fmt.Errorf("%w, found %s", ErrNotAnObject, jsonKind(object))
+	}
+
+	for i = skipSpace(object, i+1); i < len(object) && object[i] != '}'; {
+		if object[i] != '"' {
+			return fmt.Errorf("%w: expected a key at offset %d", ErrInvalidJson, i)
+		}
+
+		keyEnd, err := stringEnd(object, i)
+		if err != nil {
+			return err
+		}
+		at, key := i, object[i:keyEnd]
+
+		if i = skipSpace(object, keyEnd); i >= len(object) || object[i] != ':' {
+			return fmt.Errorf("%w: expected : at offset %d", ErrInvalidJson, i)
+		}
+
+		start := skipSpace(object, i+1)
+		end, err := valueEnd(object, start)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := fn(key, at, start, end); err != nil || !ok {
+			return err
+		}
+
+		if i, err = nextItem(object, end, '}'); err != nil {
+			return err
+		}
+	}
+
+	if i >= len(object) {
+		return fmt.Errorf("%w: unterminated object", ErrInvalidJson)
+	}
+
+	return nil
+}
+
+// eachElement calls fn with every element of an array in order, iteration stops once fn returns false
+func eachElement(array []byte, fn func(element []byte) (bool, error)) error {
+	return eachElementAt(array, func(start, end int) (bool, error) {
+		return fn(array[start:end:end])
+	})
+}
+
+// eachElementAt is eachElement for callers that need to know where the elements are, start and end are their bounds in array
+func eachElementAt(array []byte, fn func(start, end int) (bool, error)) error {
+	i := skipSpace(array, 0)
+	if i >= len(array) || array[i] != '[' {
+		return fmt.Errorf("%w, found %s", ErrNotAnArray, jsonKind(array))
+	}
+
+	for i = skipSpace(array, i+1); i < len(array) && array[i] != ']'; {
+		end, err := valueEnd(array, i)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := fn(i, end); err != nil || !ok {
+			return err
+		}
+
+		if i, err = nextItem(array, end, ']'); err != nil {
+			return err
+		}
+	}
+
+	if i >= len(array) {
+		return fmt.Errorf("%w: unterminated array", ErrInvalidJson)
+	}
+
+	return nil
+}
+
+// decodeKey unquotes a raw object key, only keys with escapes are allocated
+func decodeKey(raw []byte) (string, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1 : len(raw)-1]), nil
+	}
+
+	var key string
+	err := json.Unmarshal(raw, &key)
+	return key, err
+}
+
+// keyEquals reports if the raw quoted key is key
+func keyEquals(raw []byte, key string) (bool, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1:len(raw)-1]) == key, nil
+	}
+
+	decoded, err := decodeKey(raw)
+	return decoded == key, err
+}
+
+// lookupKey returns the value of key in object, when the key is there more than once the last one is used
+func lookupKey(object json.RawMessage, key string) (value json.RawMessage, err error) {
+	err = eachMember(object, func(k, v []byte) (bool, error) {
+		ok, err := keyEquals(k, key)
+		if ok {
+			value = v
+		}
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return nil, fmt.Errorf("%w %s", ErrCantFindField, key)
+	}
+
+	return
+}
+
+// sameKey reports if two raw quoted keys are the same key once unescaped
+func sameKey(a, b []byte) (bool, error) {
+	if bytes.IndexByte(a, '\\') < 0 && bytes.IndexByte(b, '\\') < 0 {
+		return bytes.Equal(a, b), nil
+	}
+
+	key, err := decodeKey(a)
+	if err != nil {
+		return false, err
+	}
+
+	return keyEquals(b, key)
+}
+
+// memberKeys remembers the keys of an object seen so far to find the ones that are there more than once,
+// small objects are searched and big ones use a map
+type memberKeys struct {
+	keys      [][]byte
+	positions map[string]int
+}
+
+// add returns the position of the first copy of the raw key, keys seen for the first time are added at the end
+func (s *memberKeys) add(k []byte) (at int, seen bool, err error) {
+	if s.positions != nil {
+		key, err := decodeKey(k)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if i, ok := s.positions[key]; ok {
+			return i, true, nil
+		}
+		s.positions[key] = len(s.keys)
+	} else {
+		for i, existing := range s.keys {
+			if same, err := sameKey(existing, k); err != nil {
+				return 0, false, err
+			} else if same {
+				return i, true, nil
+			}
+		}
+	}
+
+	s.keys = append(s.keys, k)
+	if len(s.keys) == 16 && s.positions == nil {
+		s.positions = make(map[string]int, 32)
+		for i, existing := range s.keys {
+			key, err := decodeKey(existing)
+			if err != nil {
+				return 0, false, err
+			}
+			s.positions[key] = i
+		}
+	}
+
+	return len(s.keys) - 1, false, nil
+}
+
+// uniqueMembers returns the raw keys and values of an object. A key thats there more than once is kept at its
+// first position with its last value, the same as decoding the object into a map would keep it
+func uniqueMembers(object []byte) (keys [][]byte, values []json.RawMessage, err error) {
+	var seen memberKeys
+	err = eachMember(object, func(k, v []byte) (bool, error) {
+		at, dup, err := seen.add(k)
+		if err != nil {
+			return false, err
+		}
+
+		if dup {
+			values[at] = v
+		} else {
+			values = append(values, v)
+		}
+
+		return true, nil
+	})
+
+	return seen.keys, values, err
+}
+
+// decodeArray returns the elements of a json array, any other value gives ErrNotAnArray
+func decodeArray(object json.RawMessage) (arr []json.RawMessage, err error) {
+	arr = []json.RawMessage{}
+	err = eachElement(object, func(element []byte) (bool, error) {
+		arr = append(arr, element)
+		return true, nil
+	})
+
+	return
+}
+
+// elementIndex returns where index i is in an array of n elements, negative indexes count from the end
+func elementIndex(i, n int) (int, error) {
+	at := i
+	if at < 0 {
+		at += n
+	}
+
+	if at < 0 || at >= n {
+		return 0, fmt.Errorf("%w %d, array has %d elements", ErrInvalidIndex, i, n)
+	}
+
+	return at, nil
+}
+
+// arrayElement returns the element at index i of an array and where it is, negative indexes count from the end
+func arrayElement(object json.RawMessage, i int) (json.RawMessage, int, error) {
+	// Non negative indexes dont need the whole array
+	if i >= 0 {
+		var value json.RawMessage
+		n := 0
+		err := eachElement(object, func(element []byte) (bool, error) {
+			if n == i {
+				value = element
+			}
+			n++
+			return value == nil, nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if value == nil {
+			_, err = elementIndex(i, n)
+			return nil, 0, err
+		}
+
+		return value, i, nil
+	}
+
+	arr, err := decodeArray(object)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	at, err := elementIndex(i, len(arr))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return arr[at], at, nil
+}
+
+type member struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// objectMembers returns the members of a json object in document order, every key is there once
+func objectMembers(object json.RawMessage) (members []member, err error) {
+	keys, values, err := uniqueMembers(object)
+	if err != nil {
+		return nil, err
+	}
+
+	members = make([]member, len(keys))
+	for i, k := range keys {
+		key, err := decodeKey(k)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = member{Key: key, Value: values[i]}
+	}
+
+	return
+}
+
+// wildcardValues returns the values of an object in document order, or the elements of an array
+func wildcardValues(object json.RawMessage) (values []json.RawMessage, err error) {
+	if jsonKind(object) == "array" {
+		return decodeArray(object)
+	}
+
+	_, values, err = uniqueMembers(object)
+	return
+}
+
+// appendCompact appends value to dst without the whitespace outside of strings
+func appendCompact(dst []byte, value []byte) []byte {
+	inString := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case inString && c == '\\' && i+1 < len(value):
+			dst = append(dst, c, value[i+1])
+			i++
+			continue
+		case c == '"':
+			inString = !inString
+		case !inString && (c == ' ' || c == '\n' || c == '\r' || c == '\t'):
+			continue
+		}
+
+		dst = append(dst, c)
+	}
+
+	return dst
+}
+
+// joinArray writes values as a compact json array
+func joinArray(values []json.RawMessage) json.RawMessage {
+	size := 2
+	for _, v := range values {
+		size += len(v) + 1
+	}
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, '[')
+	for i, v := range values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendCompact(buf, v)
+	}
+
+	return append(buf, ']')
+}
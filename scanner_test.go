@@ -0,0 +1,345 @@
+package rjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+	"github.com/goccy/go-json"
+)
+
+func TestScanner(t *testing.T) {
+	data := []byte(` {
+		"a" : { "b" :[ 1 , { "c" : "x y" } ] } ,
+		"esc\"aped": "1",
+		"brackets": "[{]}",
+		"nums": [-1.5e3, 0, true, null]
+	} `)
+
+	cases := map[string]string{
+		"a.b[1].c":      `"x y"`,
+		"a.b[1]":        `{ "c" : "x y" }`,
+		"a.b":           `[ 1 , { "c" : "x y" } ]`,
+		"a.b[]":         `[1,{"c":"x y"}]`,
+		`"esc\"aped"`:   `"1"`,
+		"brackets":      `"[{]}"`,
+		"nums[0]":       `-1.5e3`,
+		"nums[-1]":      `null`,
+		"nums[-2]":      `true`,
+		"a..c":          `["x y"]`,
+		"a.b | length":  `2`,
+		"nums | length": `4`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	invalid := map[string]string{
+		"":                     "a",
+		"   ":                  "a",
+		`{"a": [1, 2}`:         "a[]",
+		`{"a": "unterminated}`: "a",
+		`{"a" 1}`:              "a",
+		`{"a": [1 2]}`:         "a[1]",
+		`{1: 2}`:               "a",
+		`{"a": tru}`:           "a",
+		`{"a": 01}`:            "a",
+		`{"a": 1x}`:            "a",
+		`{"a": "\q"}`:          "a",
+		`{"a": "\u12"}`:        "a",
+		`{"a": 1} trailing`:    "a",
+		`{"a": 1}}`:            "a",
+		`{"a": 1,}`:            "a",
+		`{"a": 1, "b": [}]}`:   "a",
+		`{"a": 1, "b": nul}`:   "a",
+	}
+
+	for data, tag := range invalid {
+		_, err := QueryJson([]byte(data), tag)
+		assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("%q: expected ErrInvalidJson, got %v", data, err))
+
+		err = Get([]byte(data), tag).Err()
+		assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("%q: expected ErrInvalidJson from Get, got %v", data, err))
+
+		var out struct {
+			A any `rjson:"a"`
+		}
+		err = Unmarshal([]byte(data), &out)
+		assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("%q: expected ErrInvalidJson from Unmarshal, got %v", data, err))
+	}
+
+	_, err := Get([]byte(`{"a": tru}`), "a").Bool()
+	assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("expected ErrInvalidJson, got %v", err))
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	// Like decoding into a map, the last value of a key wins and it stays where it was first seen
+	data := []byte(`{"a": 1, "b": {"c": 1}, "a": 2, "b": {"d": 2}, "\u0061": 3}`)
+
+	cases := map[string]string{
+		"a":          `3`,
+		"b":          `{"d": 2}`,
+		"*":          `[3,{"d":2}]`,
+		"..d":        `[2]`,
+		"..c":        `[]`,
+		"@ | length": `2`,
+		"@ | keys":   `["a","b"]`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+
+		res, err = MustCompile(tag).query(&evaluator{root: data, index: newIndex()}, data)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+		assert.Equals(string(res), expected, fmt.Sprintf("%s with an index: '%s' is not '%s'", tag, res, expected))
+	}
+
+	// Descent takes what it finds in the last copy at the place of the first one
+	nested := []byte(`{"x": {"a": 1}, "a": 0, "x": {"b": {"a": 2}, "a": 3}}`)
+	res, err := QueryJson(nested, "..a")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `[2,3,0]`)
+
+	matches, err := QueryAll(nested, "..a")
+	assert.Equals(err, nil)
+	var paths []string
+	for _, m := range matches {
+		paths = append(paths, m.Path.String())
+	}
+	assert.Equals(paths, []string{"x.b.a", "x.a", "a"})
+
+	var out struct {
+		A int `rjson:"a"`
+		D int `rjson:"b.d"`
+	}
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(out.A, 3)
+	assert.Equals(out.D, 2)
+
+	members, err := Get(data, "@").Map()
+	assert.Equals(err, nil)
+	assert.Equals(members["a"].Raw(), json.RawMessage(`3`))
+
+	// Streams keep every copy read before they stop, z is never found so the whole object is read
+	res, err = QueryReader(bytes.NewReader(data), "z | b")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `{"d": 2}`)
+
+	// Many keys are tracked by name instead of being compared one by one
+	big := []byte(`{`)
+	for i := range 20 {
+		big = fmt.Appendf(big, `"k%d": %d, `, i, i)
+	}
+	big = append(big, `"k3": "last"}`...)
+
+	res, err = QueryJson(big, "k3")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `"last"`)
+
+	res, err = QueryJson(big, "@ | length")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `20`)
+}
+
+func TestIndex(t *testing.T) {
+	data := []byte(`{"a": {"b": [{"c": 1}, {"c": 2}], "d": "x"}, "e": true}`)
+	e := &evaluator{root: data, index: newIndex()}
+
+	cases := map[string]string{
+		"a.b[0].c": `1`,
+		"a.b[-].c": `2`,
+		"a.b[].c":  `[1,2]`,
+		"a.d":      `"x"`,
+		"a.*":      `[[{"c":1},{"c":2}],"x"]`,
+		"e":        `true`,
+	}
+
+	assert.TestState = t
+	for path, expected := range cases {
+		res, err := MustCompile(path).query(e, data)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", path, res, expected))
+	}
+
+	// The root, a, a.b and both of its elements
+	assert.Equals(len(e.index.containers), 5)
+
+	_, err := MustCompile("a.missing").query(e, data)
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+
+	_, err = MustCompile("a.d[0]").query(e, data)
+	assert.Assert(errors.Is(err, ErrNotAnArray), fmt.Sprintf("expected ErrNotAnArray, got %v", err))
+}
+
+// benchmarkDocument builds a document similar to the scraped payloads the scanner is meant for, roughly 5 MB
+func benchmarkDocument() []byte {
+	var b strings.Builder
+	b.WriteString(`{"items": [`)
+	for i := range 20000 {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, `{"id": %d, "name": "item %d", "tags": ["a", "b", "c"], "meta": {"views": %d, "description": "%s"}}`, i, i, i*3, strings.Repeat("lorem ipsum ", 15))
+	}
+	b.WriteString(`], "total": 20000}`)
+
+	return []byte(b.String())
+}
+
+// legacyQuery is the evaluator from before the scanner, every step decodes the current value again. Kept to compare against
+func legacyQuery(data []byte, segments []Segment) (object json.RawMessage, err error) {
+	if err = json.Unmarshal(data, &object); err != nil {
+		return
+	}
+
+	for i, seg := range segments {
+		switch seg.Kind {
+		case KeySegment:
+			var obj map[string]json.RawMessage
+			if err = json.Unmarshal(object, &obj); err != nil {
+				return
+			}
+
+			v, ok := obj[seg.Key]
+			if !ok {
+				return nil, fmt.Errorf("%w %s", ErrCantFindField, seg.Key)
+			}
+			object = v
+		case IndexSegment:
+			var arr []json.RawMessage
+			if err = json.Unmarshal(object, &arr); err != nil {
+				return
+			}
+
+			if seg.Index < 0 || seg.Index >= len(arr) {
+				return nil, fmt.Errorf("%w %d", ErrInvalidIndex, seg.Index)
+			}
+			object = arr[seg.Index]
+		case IteratorSegment:
+			var arr []json.RawMessage
+			if err = json.Unmarshal(object, &arr); err != nil {
+				return
+			}
+
+			result := make([]json.RawMessage, 0, len(arr))
+			for _, row := range arr {
+				var bs []byte
+				if bs, err = row.MarshalJSON(); err != nil {
+					return
+				}
+
+				var v json.RawMessage
+				if v, err = legacyQuery(bs, segments[i+1:]); isMissing(err) {
+					continue
+				} else if err != nil {
+					return
+				}
+				result = append(result, v)
+			}
+
+			return json.Marshal(result)
+		}
+	}
+
+	return
+}
+
+var benchmarkPaths = []string{
+	"total",
+	"items[19999].name",
+	"items[].id",
+	"items[].meta.views",
+}
+
+func BenchmarkQuery(b *testing.B) {
+	data := benchmarkDocument()
+
+	for _, path := range benchmarkPaths {
+		p := MustCompile(path)
+
+		b.Run("scanner/"+path, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := p.Query(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run("legacy/"+path, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := legacyQuery(data, p.Alternatives[0].Segments); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	data := benchmarkDocument()
+
+	var out struct {
+		Total int      `rjson:"total"`
+		Last  string   `rjson:"items[-].name"`
+		Ids   []int    `rjson:"items[].id"`
+		Tags  []string `rjson:"items[0].tags"`
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalFields has many fields sharing a prefix, the shared index only scans it once
+func BenchmarkUnmarshalFields(b *testing.B) {
+	data := benchmarkDocument()
+
+	var out struct {
+		Id          int      `rjson:"items[-].id"`
+		Name        string   `rjson:"items[-].name"`
+		Tags        []string `rjson:"items[-].tags"`
+		FirstTag    string   `rjson:"items[-].tags[0]"`
+		LastTag     string   `rjson:"items[-].tags[-]"`
+		Views       int      `rjson:"items[-].meta.views"`
+		Description string   `rjson:"items[-].meta.description"`
+		Total       int      `rjson:"total"`
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
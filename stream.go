@@ -0,0 +1,1140 @@
+package rjson
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/goccy/go-json"
+)
+
+// Streaming works in two steps. The paths are followed while the document is read, the values they lead to
+// are kept and everything else is skipped without being stored. Iterators, wildcards, slices and filters are
+// followed for every element they select, so only the parts of the elements the rest of the path needs are kept.
+// Elements counted from the end are held in a ring until the array ends and filters are checked against each
+// element as its read, so the elements they drop arent kept. The kept values are put together into a sparse
+// copy of the document, which the paths are then evaluated against the usual way
+
+// streamNode is a step of the paths being streamed, children are looked up by key in objects and by index in arrays
+type streamNode struct {
+	keys     map[string]*streamNode
+	indexes  map[int]*streamNode
+	each     *streamNode  // Followed for the values of an array or object kept selects, the named children include its steps too
+	kept     keptElements // Which values each is kept for
+	capture  bool         // The whole value is kept
+	repeated bool         // Below an each step, its followed once per element so its not counted as a capture
+	resolved bool         // Captured, or known to not exist in the document
+}
+
+// keptElements selects the values of an array or object the each step is kept for
+type keptElements struct {
+	all     bool          // Every value, for iterators and wildcards. Objects only keep their values for this
+	last    int           // The elements counted from the end, theyre held in a ring until the array ends
+	spans   []elementSpan // The elements slices with bounds from the start select
+	filters []FilterExpr  // The elements one of the filters matches
+	end     int           // Elements from here on are past every index and span, set by finish
+}
+
+// elementSpan is a slice selecting every step-th element from start up to end
+type elementSpan struct {
+	start, end, step int
+}
+
+func (s elementSpan) contains(i int) bool {
+	return i >= s.start && i < s.end && (i-s.start)%s.step == 0
+}
+
+// streamElement is an element of an array being walked thats kept, at is its index
+type streamElement struct {
+	at    int
+	value []byte
+}
+
+// add registers the steps of segments, the value they lead to is captured
+func (n *streamNode) add(segments []Segment) {
+	if end, _ := n.follow(segments); end != nil {
+		end.setCapture()
+	}
+}
+
+// follow registers the steps of segments and returns the node the value they lead to is read through,
+// iterated tells if the segments resolve to an array made of that node for every element they went through.
+// The end is nil when the segments cant lead anywhere, e.g a key looked up in the result of a filter
+func (n *streamNode) follow(segments []Segment) (end *streamNode, iterated bool) {
+	for i, seg := range segments {
+		// Anything below a captured value is part of it
+		if n.capture {
+			return n, iterated
+		}
+
+		switch seg.Kind {
+		case KeySegment:
+			n = n.keyChild(seg.Key, nil)
+		case IndexSegment, LastSegment:
+			switch {
+			case seg.Kind == LastSegment:
+				n = n.lastChild(1)
+			case seg.Index >= 0:
+				n = n.indexChild(seg.Index, nil)
+			default:
+				n = n.lastChild(-seg.Index)
+			}
+		case PointerSegment:
+			// Pointer segments are keys or indexes depending on the document, so both lead to the same node
+			child := n.keyChild(seg.Key, nil)
+			if index, ok := pointerIndex(seg.Key); ok {
+				n.indexChild(index, child)
+			}
+			n = child
+		case IteratorSegment, WildcardSegment:
+			n, iterated = n.everyChild(), true
+		case SliceSegment:
+			n, iterated = n.sliceChild(seg.Slice), true
+		case FilterSegment:
+			// The elements have to keep what the filter looks at, the rest of the path runs on the filtered array
+			element := n.filterChild(seg.Filter)
+			if seg.Filter != nil {
+				element.addFilter(*seg.Filter)
+			}
+
+			end, inner := element.elements(segments[i+1:])
+			return end, iterated || inner
+		case DescentSegment:
+			// Matches can be at any depth
+			n.setCapture()
+			return n, iterated
+		}
+	}
+
+	return n, iterated
+}
+
+// elements follows segments run against an array made of n for every element, like the result of a filter
+func (n *streamNode) elements(segments []Segment) (end *streamNode, iterated bool) {
+	if len(segments) == 0 {
+		return n, true
+	}
+
+	switch seg := segments[0]; seg.Kind {
+	case IndexSegment, LastSegment, PointerSegment:
+		return n.follow(segments[1:])
+	case IteratorSegment, SliceSegment, WildcardSegment:
+		end, _ := n.follow(segments[1:])
+		return end, true
+	case FilterSegment:
+		if seg.Filter != nil {
+			n.addFilter(*seg.Filter)
+		}
+		return n.elements(segments[1:])
+	case DescentSegment:
+		n.setCapture()
+		return n, true
+	case CurrentSegment:
+		return n.elements(segments[1:])
+	}
+
+	// Keys fail on the array whatever its elements are
+	return nil, false
+}
+
+// addFilter captures the values the filter compares, paths from the root are captured with the whole document
+func (n *streamNode) addFilter(expr FilterExpr) {
+	for _, child := range expr.Children {
+		n.addFilter(child)
+	}
+
+	for _, operand := range expr.Operands {
+		if operand.Current {
+			n.add(operand.Segments)
+		}
+	}
+}
+
+// setCapture marks the whole value as needed, its steps arent needed anymore
+func (n *streamNode) setCapture() {
+	n.capture = true
+	n.keys, n.indexes, n.each, n.kept = nil, nil, nil, keptElements{}
+}
+
+func (n *streamNode) keyChild(key string, child *streamNode) *streamNode {
+	if n.keys == nil {
+		n.keys = make(map[string]*streamNode)
+	}
+
+	if existing, ok := n.keys[key]; ok {
+		return existing
+	} else if child == nil {
+		child = &streamNode{}
+	}
+
+	n.keys[key] = child
+	return child
+}
+
+func (n *streamNode) indexChild(i int, child *streamNode) *streamNode {
+	if n.indexes == nil {
+		n.indexes = make(map[int]*streamNode)
+	}
+
+	if existing, ok := n.indexes[i]; ok {
+		return existing
+	} else if child == nil {
+		child = &streamNode{}
+	}
+
+	n.indexes[i] = child
+	return child
+}
+
+func (n *streamNode) eachChild() *streamNode {
+	if n.each == nil {
+		n.each = &streamNode{}
+	}
+
+	return n.each
+}
+
+// everyChild returns the node followed for every value
+func (n *streamNode) everyChild() *streamNode {
+	n.kept.all = true
+	return n.eachChild()
+}
+
+// lastChild returns the node followed for the last count elements
+func (n *streamNode) lastChild(count int) *streamNode {
+	n.kept.last = max(n.kept.last, count)
+	return n.eachChild()
+}
+
+// sliceChild returns the node followed for the elements r selects. Bounds from the start select elements by
+// index and a start from the end the elements counted from the end, any other slice follows every element
+func (n *streamNode) sliceChild(r SliceRange) *streamNode {
+	span := elementSpan{end: math.MaxInt, step: 1}
+	if r.Start != nil {
+		span.start = *r.Start
+	}
+	if r.End != nil {
+		span.end = *r.End
+	}
+	if r.Step != nil {
+		span.step = *r.Step
+	}
+
+	switch {
+	case span.step <= 0:
+		return n.everyChild()
+	case span.start < 0 && (r.End == nil || span.end < 0):
+		return n.lastChild(-span.start)
+	case span.start >= 0 && span.end >= 0:
+		n.kept.spans = append(n.kept.spans, span)
+		return n.eachChild()
+	}
+
+	return n.everyChild()
+}
+
+// filterChild returns the node followed for the elements filter matches
+func (n *streamNode) filterChild(filter *FilterExpr) *streamNode {
+	if filter == nil {
+		return n.everyChild()
+	}
+
+	n.kept.filters = append(n.kept.filters, *filter)
+	return n.eachChild()
+}
+
+// merge adds the steps of other to n
+func (n *streamNode) merge(other *streamNode) {
+	if n.capture {
+		return
+	} else if other.capture {
+		n.setCapture()
+		return
+	}
+
+	for key, child := range other.keys {
+		n.keyChild(key, nil).merge(child)
+	}
+
+	for i, child := range other.indexes {
+		n.indexChild(i, nil).merge(child)
+	}
+
+	if other.each != nil {
+		n.eachChild().merge(other.each)
+	}
+
+	n.kept.all = n.kept.all || other.kept.all
+	n.kept.last = max(n.kept.last, other.kept.last)
+	n.kept.spans = append(n.kept.spans, other.kept.spans...)
+	n.kept.filters = append(n.kept.filters, other.kept.filters...)
+}
+
+// finish is called once every path has been added, the steps for every element are added to the named
+// children and the nodes below them are marked as repeated
+func (n *streamNode) finish(repeated bool) {
+	n.repeated = repeated
+	n.kept.finish(n.indexes)
+	if n.each != nil {
+		for _, child := range n.keys {
+			child.merge(n.each)
+		}
+
+		for _, child := range n.indexes {
+			child.merge(n.each)
+		}
+
+		n.each.finish(true)
+	}
+
+	for _, child := range n.keys {
+		child.finish(repeated)
+	}
+
+	for _, child := range n.indexes {
+		child.finish(repeated)
+	}
+}
+
+// finish sets where the indexes and spans end. Dropping elements a filter doesnt match would move the elements
+// counted from the end, so both together keep every element
+func (k *keptElements) finish(indexes map[int]*streamNode) {
+	if k.last > 0 && len(k.filters) > 0 {
+		k.all = true
+	}
+
+	if k.all {
+		k.last, k.spans, k.filters = 0, nil, nil
+	}
+
+	k.end = 0
+	for i := range indexes {
+		k.end = max(k.end, i+1)
+	}
+
+	for _, span := range k.spans {
+		k.end = max(k.end, span.end)
+	}
+}
+
+// follows reports if the each step is followed for the element at i
+func (k *keptElements) follows(i int) bool {
+	if k.all || k.last > 0 || len(k.filters) > 0 {
+		return true
+	}
+
+	for _, span := range k.spans {
+		if span.contains(i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bounded reports if only indexes and spans select elements, so nothing past the end of them is needed
+func (k *keptElements) bounded() bool {
+	return !k.all && k.last == 0 && len(k.filters) == 0
+}
+
+// captures counts the values that have to be found before reading can stop, a node with steps for every
+// element counts once since its only done when its container ends
+func (n *streamNode) captures() (count int) {
+	if n.repeated {
+		return 0
+	} else if n.capture || n.each != nil {
+		count = 1
+	}
+
+	// Pointer nodes are in both maps, they are only counted through the keys
+	counted := make(map[*streamNode]bool)
+	for _, child := range n.keys {
+		counted[child] = true
+		count += child.captures()
+	}
+
+	for _, child := range n.indexes {
+		if !counted[child] {
+			count += child.captures()
+		}
+	}
+
+	return
+}
+
+// needsRoot reports if the alternative refers to the root of the document anywhere after its start
+func needsRoot(alternative Alternative) bool {
+	for i, seg := range alternative.Segments {
+		if (i > 0 && seg.Kind == RootSegment) || (seg.Filter != nil && filterNeedsRoot(*seg.Filter)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func filterNeedsRoot(expr FilterExpr) bool {
+	for _, child := range expr.Children {
+		if filterNeedsRoot(child) {
+			return true
+		}
+	}
+
+	for _, operand := range expr.Operands {
+		if operand.Root || needsRoot(Alternative{Segments: operand.Segments}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRootAnchored reports if the alternative starts from the root of the document instead of the current value
+func isRootAnchored(alternative Alternative) bool {
+	return len(alternative.Segments) > 0 && alternative.Segments[0].Kind == RootSegment
+}
+
+// streamPath builds the steps needed to evaluate path against a streamed document
+func streamPath(path *Path) *streamNode {
+	root := &streamNode{}
+	for _, alternative := range path.Alternatives {
+		if needsRoot(alternative) {
+			root.setCapture()
+			continue
+		}
+
+		root.add(alternative.Segments)
+	}
+
+	return root
+}
+
+// streamStruct adds the steps needed to fill the tagged fields of t, base is the node the struct is read through.
+// Types that contain themselves are captured where they repeat, visited holds the types base is inside of
+func streamStruct(root, base *streamNode, t reflect.Type, visited map[reflect.Type]bool) error {
+	if visited[t] {
+		// The tags from the root were added where the type was first seen
+		base.setCapture()
+		return nil
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get(TagName)
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		isStruct := field.Type.Kind() == reflect.Struct
+		isStructSlice := field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct
+
+		if isStruct && tag == "." {
+			if err := streamStruct(root, base, field.Type, visited); err != nil {
+				return err
+			}
+			continue
+		}
+
+		path, err := compileTag(tag)
+		if err != nil {
+			return fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+		}
+
+		for _, alternative := range path.Alternatives {
+			if needsRoot(alternative) {
+				root.setCapture()
+				continue
+			}
+
+			start := base
+			if isRootAnchored(alternative) {
+				start = root
+			}
+
+			if !isStruct && !isStructSlice {
+				start.add(alternative.Segments)
+				continue
+			}
+
+			end, iterated := start.follow(alternative.Segments)
+			elem := field.Type
+
+			switch {
+			case end == nil:
+				continue
+			case len(path.Functions) > 0:
+				// Functions change the value, so the struct cant be filled from its parts. Its fields only
+				// need steps if they start from the root
+				end.setCapture()
+				end = &streamNode{capture: true}
+			case isStructSlice && !iterated:
+				end = end.everyChild()
+			}
+
+			if isStructSlice {
+				elem = elem.Elem()
+			}
+
+			if err = streamStruct(root, end, elem, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// errStreamDone stops reading once every captured value has been found
+var errStreamDone = errors.New("every path is resolved")
+
+type streamReader struct {
+	r         *bufio.Reader
+	remaining int    // Captures not resolved yet
+	depth     int    // Containers entered and not left, after stopping early these are left unread
+	key       []byte // Reused for the keys of objects
+	scalar    []byte // Reused for the numbers, true, false and null being read
+	closers   []byte // Reused by readValue for the containers its in
+	filters   evaluator
+}
+
+func newStreamReader(r io.Reader) *streamReader {
+	return &streamReader{r: bufio.NewReader(r), key: make([]byte, 0, 64)}
+}
+
+func unexpectedEnd(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("%w: unexpected end of input", ErrInvalidJson)
+	}
+
+	return err
+}
+
+// peek skips whitespace and returns the next byte without consuming it
+func (s *streamReader) peek() (byte, error) {
+	_, c, err := s.space(nil)
+	return c, err
+}
+
+// space is like peek but the whitespace it skips is appended to dst if dst isnt nil
+func (s *streamReader) space(dst []byte) ([]byte, byte, error) {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return dst, 0, err
+		}
+
+		if c != ' ' && c != '\n' && c != '\r' && c != '\t' {
+			return dst, c, s.r.UnreadByte()
+		}
+
+		if dst != nil {
+			dst = append(dst, c)
+		}
+	}
+}
+
+// expect consumes the next byte, which has to be c
+func (s *streamReader) expect(c byte) error {
+	next, err := s.peek()
+	if err != nil {
+		return unexpectedEnd(err)
+	} else if next != c {
+		return fmt.Errorf("%w: expected %q, found %q", ErrInvalidJson, c, next)
+	}
+
+	_, err = s.r.ReadByte()
+	return err
+}
+
+// readString reads the string starting at the next byte, its appended to dst if dst isnt nil
+func (s *streamReader) readString(dst []byte) ([]byte, error) {
+	if err := s.expect('"'); err != nil {
+		return dst, err
+	}
+
+	keep := dst != nil
+	if keep {
+		dst = append(dst, '"')
+	}
+
+	escaped, hex := false, 0
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return dst, unexpectedEnd(err)
+		}
+
+		if keep {
+			dst = append(dst, c)
+		}
+
+		switch {
+		case hex > 0:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return dst, fmt.Errorf("%w: invalid escape in string", ErrInvalidJson)
+			}
+			hex--
+		case escaped:
+			escaped = false
+			if c == 'u' {
+				hex = 4
+			} else if !validEscape([]byte{'\\', c}, 0) {
+				return dst, fmt.Errorf("%w: invalid escape in string", ErrInvalidJson)
+			}
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return dst, nil
+		case c < 0x20:
+			return dst, fmt.Errorf("%w: control character in string", ErrInvalidJson)
+		}
+	}
+}
+
+// readValue reads the next value, its appended to dst as written if dst isnt nil
+func (s *streamReader) readValue(dst []byte) ([]byte, error) {
+	// Whitespace before the value isnt part of it
+	if _, err := s.peek(); err != nil {
+		return dst, unexpectedEnd(err)
+	}
+
+	keep := dst != nil
+	closers := s.closers[:0] // Closing brackets of the containers being read
+	for {
+		var c byte
+		var err error
+		if dst, c, err = s.space(dst); err != nil {
+			return dst, unexpectedEnd(err)
+		}
+
+		switch c {
+		case '{', '[':
+			if _, err = s.r.ReadByte(); err != nil {
+				return dst, err
+			}
+			if keep {
+				dst = append(dst, c)
+			}
+
+			closer := byte(']')
+			if c == '{' {
+				closer = '}'
+			}
+
+			closers = append(closers, closer)
+			if dst, c, err = s.space(dst); err != nil {
+				return dst, unexpectedEnd(err)
+			} else if c != closer && closer == '}' {
+				if dst, err = s.readMemberKey(dst); err != nil {
+					return dst, err
+				}
+			}
+
+			// Empty containers end right away
+			if c != closer {
+				continue
+			}
+		case '"':
+			dst, err = s.readString(dst)
+		case ',', ':', '}', ']':
+			return dst, fmt.Errorf("%w: unexpected %q", ErrInvalidJson, c)
+		default:
+			dst, err = s.readScalar(dst)
+		}
+
+		if err != nil {
+			return dst, err
+		}
+
+		// After a value either the container it is in goes on or ends
+		for len(closers) > 0 {
+			if dst, c, err = s.space(dst); err != nil {
+				return dst, unexpectedEnd(err)
+			}
+
+			closer := closers[len(closers)-1]
+			if c != ',' && c != closer {
+				return dst, fmt.Errorf("%w: unexpected %q", ErrInvalidJson, c)
+			}
+
+			if _, err = s.r.ReadByte(); err != nil {
+				return dst, err
+			}
+			if keep {
+				dst = append(dst, c)
+			}
+
+			if c == closer {
+				closers = closers[:len(closers)-1]
+				continue
+			}
+
+			if closer == '}' {
+				if dst, err = s.readMemberKey(dst); err != nil {
+					return dst, err
+				}
+			}
+			break
+		}
+
+		if len(closers) == 0 {
+			s.closers = closers
+			return dst, nil
+		}
+	}
+}
+
+// readMemberKey reads the key of an object member and the : after it, they are appended to dst if dst isnt nil
+func (s *streamReader) readMemberKey(dst []byte) ([]byte, error) {
+	dst, c, err := s.space(dst)
+	if err != nil {
+		return dst, unexpectedEnd(err)
+	} else if c != '"' {
+		return dst, fmt.Errorf("%w: expected a key, found %q", ErrInvalidJson, c)
+	}
+
+	if dst, err = s.readString(dst); err != nil {
+		return dst, err
+	}
+
+	if dst, c, err = s.space(dst); err != nil {
+		return dst, unexpectedEnd(err)
+	} else if c != ':' {
+		return dst, fmt.Errorf("%w: expected ':', found %q", ErrInvalidJson, c)
+	}
+
+	_, err = s.r.ReadByte()
+	if dst != nil {
+		dst = append(dst, ':')
+	}
+
+	return dst, err
+}
+
+// readScalar reads the number, true, false or null starting at the next byte, its appended to dst if dst isnt nil
+func (s *streamReader) readScalar(dst []byte) ([]byte, error) {
+	s.scalar = s.scalar[:0]
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return dst, err
+		}
+
+		if isDelimiter(c) {
+			if err = s.r.UnreadByte(); err != nil {
+				return dst, err
+			}
+			break
+		}
+
+		s.scalar = append(s.scalar, c)
+	}
+
+	if end, err := scalarEnd(s.scalar, 0); err != nil || end != len(s.scalar) {
+		return dst, fmt.Errorf("%w: invalid value %q", ErrInvalidJson, s.scalar)
+	}
+
+	if dst != nil {
+		dst = append(dst, s.scalar...)
+	}
+
+	return dst, nil
+}
+
+// resolve marks n and everything below it as done
+func (s *streamReader) resolve(n *streamNode) {
+	if n.resolved || n.repeated {
+		return
+	}
+	n.resolved = true
+
+	if n.capture || n.each != nil {
+		s.remaining--
+	}
+
+	for _, child := range n.keys {
+		s.resolve(child)
+	}
+
+	for _, child := range n.indexes {
+		s.resolve(child)
+	}
+}
+
+// walk reads the next value following the steps of n, the parts of it that are needed are appended to out
+func (s *streamReader) walk(n *streamNode, out []byte) ([]byte, error) {
+	if n.capture {
+		out, err := s.readValue(out)
+		if err == nil {
+			s.resolve(n)
+		}
+		return out, err
+	}
+
+	c, err := s.peek()
+	if err != nil {
+		return out, unexpectedEnd(err)
+	}
+
+	switch c {
+	case '{':
+		return s.walkObject(n, out)
+	case '[':
+		return s.walkArray(n, out)
+	}
+
+	// Scalars are kept so the paths fail the same way they would on the whole document. Only their type
+	// matters, so strings are kept empty
+	if c == '"' {
+		if _, err = s.readString(nil); err == nil {
+			out = append(out, `""`...)
+		}
+	} else {
+		out, err = s.readValue(out)
+	}
+
+	// A path that goes on past the scalar isnt resolved by it, a later copy of a duplicate key might still have it
+	if n.keys == nil && n.indexes == nil && n.each == nil {
+		s.resolve(n)
+	}
+	return out, err
+}
+
+// next consumes the separator after a member or element, done tells if it was the end of the container
+func (s *streamReader) next(close byte) (done bool, err error) {
+	c, err := s.peek()
+	if err != nil {
+		return false, unexpectedEnd(err)
+	}
+
+	switch c {
+	case ',':
+		_, err = s.r.ReadByte()
+		return false, err
+	case close:
+		_, err = s.r.ReadByte()
+		return true, err
+	}
+
+	return false, fmt.Errorf("%w: unexpected %q", ErrInvalidJson, c)
+}
+
+func (s *streamReader) walkObject(n *streamNode, out []byte) ([]byte, error) {
+	if err := s.expect('{'); err != nil {
+		return out, err
+	}
+	s.depth++
+	out = append(out, '{')
+
+	c, err := s.peek()
+	if err != nil {
+		return out, unexpectedEnd(err)
+	}
+
+	wrote := false
+	for done := c == '}'; !done; {
+		if s.key, err = s.readString(s.key[:0]); err != nil {
+			return out, err
+		}
+
+		if err = s.expect(':'); err != nil {
+			return out, err
+		}
+
+		key, err := decodeKey(s.key)
+		if err != nil {
+			return out, err
+		}
+
+		// A key thats there more than once is followed every time, the sparse document keeps
+		// each copy so the last one wins when its queried
+		child := n.keys[key]
+		if child == nil && n.kept.all {
+			child = n.each
+		}
+
+		if child != nil {
+			if wrote {
+				out = append(out, ',')
+			}
+			out = append(append(out, s.key...), ':')
+			wrote = true
+
+			if out, err = s.walk(child, out); err == nil && s.remaining == 0 {
+				err = errStreamDone
+			}
+
+			if err != nil {
+				return append(out, '}'), err
+			}
+		} else if _, err = s.readValue(nil); err != nil {
+			return out, err
+		}
+
+		if done, err = s.next('}'); err != nil {
+			return out, err
+		}
+	}
+
+	if c == '}' {
+		if _, err = s.r.ReadByte(); err != nil {
+			return out, err
+		}
+	}
+
+	s.depth--
+	s.resolve(n)
+	return append(out, '}'), nil
+}
+
+func (s *streamReader) walkArray(n *streamNode, out []byte) ([]byte, error) {
+	if err := s.expect('['); err != nil {
+		return out, err
+	}
+	s.depth++
+
+	c, err := s.peek()
+	if err != nil {
+		return out, unexpectedEnd(err)
+	}
+
+	// Kept elements are collected until the array ends, the ones counted from the end are only known then.
+	// Elements that might be among them are held in tail, which is used as a ring
+	var kept, tail []streamElement
+	element := []byte{}
+	next, past := 0, false
+	i := 0
+	for done := c == ']'; !done; i++ {
+		if !past && n.kept.bounded() && i >= n.kept.end {
+			// Past every index and span, the rest of the array is skipped
+			past = true
+			if s.resolve(n); s.remaining == 0 {
+				return n.writeElements(out, kept, tail, i), errStreamDone
+			}
+		}
+
+		child := n.indexes[i]
+		if child == nil && !past && n.kept.follows(i) {
+			child = n.each
+		}
+
+		if child != nil {
+			if element, err = s.walk(child, element[:0]); err == nil && s.remaining == 0 {
+				err = errStreamDone
+			}
+
+			switch {
+			case err != nil || s.keeps(n, i, element):
+				kept = append(kept, streamElement{at: i, value: append([]byte(nil), element...)})
+			case n.kept.last > 0 && len(tail) < n.kept.last:
+				tail = append(tail, streamElement{at: i, value: append([]byte(nil), element...)})
+			case n.kept.last > 0:
+				// The oldest element in the ring cant be among the last ones anymore
+				tail[next] = streamElement{at: i, value: append(tail[next].value[:0], element...)}
+				next = (next + 1) % len(tail)
+			}
+
+			if err != nil {
+				return n.writeElements(out, kept, append(tail[next:], tail[:next]...), i+1), err
+			}
+		} else if _, err = s.readValue(nil); err != nil {
+			return out, err
+		}
+
+		if done, err = s.next(']'); err != nil {
+			return out, err
+		}
+	}
+
+	if c == ']' {
+		if _, err = s.r.ReadByte(); err != nil {
+			return out, err
+		}
+	}
+
+	s.depth--
+	s.resolve(n)
+	return n.writeElements(out, kept, append(tail[next:], tail[:next]...), i), nil
+}
+
+// keeps reports if element, the element at i walked with the steps of n, is kept for anything but counting from the end
+func (s *streamReader) keeps(n *streamNode, i int, element []byte) bool {
+	if n.kept.all || n.indexes[i] != nil {
+		return true
+	}
+
+	for _, span := range n.kept.spans {
+		if span.contains(i) {
+			return true
+		}
+	}
+
+	// Elements before an index or span keep their place, so theyre only dropped past them
+	if len(n.kept.filters) == 0 {
+		return false
+	} else if i < n.kept.end {
+		return true
+	}
+
+	for _, filter := range n.kept.filters {
+		// Filters that fail are left for the query to report
+		if ok, err := s.filters.matchFilter(element, filter); ok || err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeElements appends the array made of the kept elements and the ones in tail among the last of length
+// elements to out. Elements before an index or span keep their place, as do all of them if elements are
+// counted from the end too, the ones between are written as null
+func (n *streamNode) writeElements(out []byte, kept, tail []streamElement, length int) []byte {
+	positional := len(n.indexes) > 0 || len(n.kept.spans) > 0
+	for len(tail) > 0 && tail[0].at < length-n.kept.last {
+		tail = tail[1:]
+	}
+
+	out = append(out, '[')
+	written, count := 0, 0
+	for len(kept) > 0 || len(tail) > 0 {
+		var element streamElement
+		if len(tail) == 0 || (len(kept) > 0 && kept[0].at < tail[0].at) {
+			element, kept = kept[0], kept[1:]
+		} else {
+			element, tail = tail[0], tail[1:]
+		}
+
+		if positional && (n.kept.last > 0 || element.at < n.kept.end) {
+			for ; written < element.at; written++ {
+				if count > 0 {
+					out = append(out, ',')
+				}
+				out = append(out, "null"...)
+				count++
+			}
+		}
+
+		if count > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, element.value...)
+		written, count = element.at+1, count+1
+	}
+
+	return append(out, ']')
+}
+
+// document reads the next value of the stream into a sparse document holding what n needs,
+// reading stops as soon as everything n needs has been found
+func (s *streamReader) document(n *streamNode) (json.RawMessage, error) {
+	n.finish(false)
+	s.remaining = n.captures()
+
+	out, err := s.walk(n, []byte{})
+	if err == errStreamDone {
+		err = nil
+	}
+
+	return out, err
+}
+
+// skipUnread reads past the rest of a value that was left early
+func (s *streamReader) skipUnread() error {
+	for s.depth > 0 {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return unexpectedEnd(err)
+		}
+
+		switch c {
+		case '"':
+			if err = s.r.UnreadByte(); err != nil {
+				return err
+			}
+			if _, err = s.readString(nil); err != nil {
+				return err
+			}
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+		}
+	}
+
+	return nil
+}
+
+// QueryReader is like QueryJson but reads the document from r. Only the values the path leads to are kept in
+// memory and reading stops once they have been found, so r might not be read until the end
+func QueryReader(r io.Reader, tag string) (json.RawMessage, error) {
+	path, err := Compile(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+	}
+
+	return path.QueryReader(r)
+}
+
+// QueryReader runs the path against a document read from r, same as the QueryReader function
+func (p *Path) QueryReader(r io.Reader) (json.RawMessage, error) {
+	s := newStreamReader(r)
+	if _, err := s.peek(); err != nil {
+		return nil, unexpectedEnd(err)
+	}
+
+	doc, err := s.document(streamPath(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Query(doc)
+}
+
+// Decoder reads json values from a stream and decodes them like Unmarshal
+type Decoder struct {
+	s *streamReader
+}
+
+// NewDecoder returns a decoder reading from r, it buffers its reads so it can read past the values it decodes
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{s: newStreamReader(r)}
+}
+
+// Decode reads the next json value from the stream and stores the tagged fields in the struct v points to.
+// Only the parts of the value the tags need are kept in memory, io.EOF is returned once there are no values left
+func (d *Decoder) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrNotAPointer
+	}
+
+	// The previous value might have been left early
+	if err := d.s.skipUnread(); err != nil {
+		return err
+	}
+
+	if _, err := d.s.peek(); err != nil {
+		return err
+	}
+
+	root := &streamNode{}
+	if err := streamStruct(root, root, reflect.Indirect(rv).Type(), make(map[reflect.Type]bool)); err != nil {
+		return err
+	}
+
+	doc, err := d.s.document(root)
+	if err != nil {
+		return err
+	}
+
+	return Unmarshal(doc, v)
+}
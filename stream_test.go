@@ -0,0 +1,307 @@
+package rjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+func TestQueryReader(t *testing.T) {
+	bs, err := os.ReadFile("test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Appendf(nil, string(bs), ">_<", "asd", 1, "mrow", "OWO")
+
+	tags := []string{
+		"uwu.nya",
+		"one.two.three.num",
+		"one.arr",
+		"one.arr[0]",
+		"one.arr[-1]",
+		"combined[].str",
+		"combined[-2].str",
+		"nestedarr[0].test[0].test",
+		"nesteditter[].thing[].a",
+		`headers["content-type"]`,
+		`headers."a.b".c`,
+		"/jarray/1/1",
+		"uwu.missing | uwu.nya",
+		"one.arr | length",
+		"combined[?(@.num > $.one.two.three.num)][].str",
+		"..mrow",
+		"combined[*].r",
+		"combined[::-1].num",
+		"combined[-].r.fields",
+		"combined[]?.r.different",
+		"one.*",
+		"nesteditter[].thing[-1].a",
+		"nesteditter[0].thing[1:] | length",
+		"combined[?(@.num > 1)][0].str",
+		"combined[?(@.r.fields)][?(@.num == 2)][].str",
+		"combined[0].r | combined[].str",
+	}
+
+	assert.TestState = t
+	for _, tag := range tags {
+		expected, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		res, err := QueryReader(bytes.NewReader(data), tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), string(expected), fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	missing := map[string]error{
+		"uwu.missing": ErrCantFindField,
+		"one.arr[5]":  ErrInvalidIndex,
+		"uwu.nya.a":   ErrNotAnObject,
+		"uwu[0]":      ErrNotAnArray,
+	}
+
+	for tag, expected := range missing {
+		_, err := QueryReader(bytes.NewReader(data), tag)
+		assert.Assert(errors.Is(err, expected), fmt.Sprintf("%s: expected %v, got %v", tag, expected, err))
+	}
+
+	invalid := map[string]string{
+		`{"a": [1, 2`:            "b",
+		`{"a": tru}`:             "a",
+		`{"b": tru, "a": 1}`:     "a",
+		`{"b": [1, 2,], "a": 1}`: "a",
+		`{"b": "\q", "a": 1}`:    "a",
+		`{"a": [1x]}`:            "a[0]",
+	}
+
+	for data, tag := range invalid {
+		_, err = QueryReader(strings.NewReader(data), tag)
+		assert.Assert(errors.Is(err, ErrInvalidJson), fmt.Sprintf("%q: expected ErrInvalidJson, got %v", data, err))
+	}
+
+	// A copy of a duplicate key the path cant go through doesnt stop it from reaching a later copy
+	duplicates := map[string]string{
+		`{"a": 1, "a": {"a": 2}}`: "a.a",
+		`{"a": "x", "a": [1, 2]}`: "a[1]",
+	}
+
+	for data, tag := range duplicates {
+		expected, err := QueryJson([]byte(data), tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		res, err := QueryReader(strings.NewReader(data), tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+		assert.Equals(string(res), string(expected), fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+}
+
+func TestStreamKeepsOnlyMatches(t *testing.T) {
+	data := `{"items": [
+		{"id": 1, "big": "xxxxxxxxxxxxxxxx", "tags": ["a", "b"]},
+		{"id": 2, "big": "xxxxxxxxxxxxxxxx", "tags": []},
+		"xxxxxxxxxxxxxxxx"
+	], "meta": {"big": "xxxxxxxxxxxxxxxx"}}`
+
+	// Iterators, wildcards, slices and filters are followed for the elements they select instead of keeping the whole array
+	cases := map[string]string{
+		"items[].id":                             `{"items":[{"id":1},{"id":2},""]}`,
+		"items[*].tags[0]":                       `{"items":[{"tags":["a"]},{"tags":[]},""]}`,
+		"items[-1].id":                           `{"items":[""]}`,
+		"items[-2].id":                           `{"items":[{"id":2},""]}`,
+		"items[-].tags[-1]":                      `{"items":[""]}`,
+		"items[1:2].id":                          `{"items":[null,{"id":2}]}`,
+		"items[:3:2].id":                         `{"items":[{"id":1},null,""]}`,
+		"items[-2:].id":                          `{"items":[{"id":2},""]}`,
+		"items[1:].id | items[-3:-1:2].big":      `{"items":[{"id":1,"big":"xxxxxxxxxxxxxxxx"},{"id":2,"big":"xxxxxxxxxxxxxxxx"},""]}`,
+		"items[0].id | items[-1].id":             `{"items":[{"id":1},null,""]}`,
+		"items[?(@.id > 1)][].id":                `{"items":[{"id":2}]}`,
+		"items[?(@.tags[0])]":                    `{"items":[{"id": 1, "big": "xxxxxxxxxxxxxxxx", "tags": ["a", "b"]}]}`,
+		"items[1].big | items[?(@.id == 1)].id":  `{"items":[{"id":1},{"id":2,"big":"xxxxxxxxxxxxxxxx"}]}`,
+		"items[?(@.id == 2)].id | items[-1].big": `{"items":[{"id":1,"big":"xxxxxxxxxxxxxxxx"},{"id":2,"big":"xxxxxxxxxxxxxxxx"},""]}`,
+		"items[0].big | items[].id":              `{"items":[{"id":1,"big":"xxxxxxxxxxxxxxxx"},{"id":2},""]}`,
+		"*.big":                                  `{"items":[],"meta":{"big":"xxxxxxxxxxxxxxxx"}}`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		doc, err := newStreamReader(strings.NewReader(data)).document(streamPath(MustCompile(tag)))
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+		assert.Equals(string(doc), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, doc, expected))
+
+		// The sparse document gives the same result as the whole one. Errors name elements by their place in
+		// the sparse document, so only the kind of error is compared
+		want, wantErr := QueryJson([]byte(data), tag)
+		res, err := QueryReader(strings.NewReader(data), tag)
+		assert.Equals(errorKind(err), errorKind(wantErr), fmt.Sprintf("%s: %v is not %v", tag, err, wantErr))
+		assert.Equals(string(res), string(want))
+	}
+
+	// Elements counted from the end and the ones filters drop arent kept, however long the array is
+	var long strings.Builder
+	long.WriteString(`{"items": [`)
+	for i := range 10000 {
+		fmt.Fprintf(&long, `{"id": %d, "big": "xxxxxxxxxxxxxxxx"},`, i)
+	}
+	long.WriteString(`{"id": -1}]}`)
+
+	sparse := map[string]string{
+		"items[-2].big":                `{"items":[{"big":"xxxxxxxxxxxxxxxx"},{}]}`,
+		"items[?(@.id == 5000)][].big": `{"items":[{"id":5000,"big":"xxxxxxxxxxxxxxxx"}]}`,
+	}
+
+	for tag, expected := range sparse {
+		doc, err := newStreamReader(strings.NewReader(long.String())).document(streamPath(MustCompile(tag)))
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+		assert.Equals(string(doc), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, doc, expected))
+	}
+
+	type Item struct {
+		Id   int      `rjson:"id"`
+		Tags []string `rjson:"tags"`
+	}
+
+	type record struct {
+		Items []Item `rjson:"items"`
+		First Item   `rjson:"items[0]"`
+		Ids   []Item `rjson:"items[]?"`
+	}
+
+	root := &streamNode{}
+	if err := streamStruct(root, root, reflect.TypeOf(record{}), make(map[reflect.Type]bool)); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := newStreamReader(strings.NewReader(data)).document(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(string(doc), `{"items":[{"id":1,"tags":["a", "b"]},{"id":2,"tags":[]},""]}`)
+
+	// Types that contain themselves are kept whole where they repeat
+	type node struct {
+		Name     string `rjson:"name"`
+		Children []node `rjson:"children"`
+	}
+
+	root = &streamNode{}
+	if err := streamStruct(root, root, reflect.TypeOf(node{}), make(map[reflect.Type]bool)); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err = newStreamReader(strings.NewReader(`{"name": "a", "x": 1, "children": [{"name": "b", "x": 2, "children": []}]}`)).document(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(string(doc), `{"name":"a","children":[{"name": "b", "x": 2, "children": []}]}`)
+}
+
+// errorKind returns the error of the package err wraps
+func errorKind(err error) error {
+	for _, kind := range []error{ErrCantFindField, ErrInvalidIndex, ErrNotAnObject, ErrNotAnArray, ErrInvalidJson, ErrMalformedSyntax, ErrInvalidFunctionInput} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+
+	return err
+}
+
+func TestQueryReaderStopsEarly(t *testing.T) {
+	// Reading past the matched values fails
+	r := io.MultiReader(
+		strings.NewReader(`{"a": {"b": [1, {"c": "found"}], "x": 1}, "d": 2, "rest": [`),
+		iotest.ErrReader(errors.New("read past the match")),
+	)
+
+	res, err := QueryReader(r, "a.b[1].c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.TestState = t
+	assert.Equals(string(res), `"found"`)
+
+	// Slices with bounds from the start stop at their end
+	r = io.MultiReader(
+		strings.NewReader(`{"a": [0, 1, 2, 3, `),
+		iotest.ErrReader(errors.New("read past the slice")),
+	)
+
+	if res, err = QueryReader(r, "a[1:3]"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equals(string(res), `[1,2]`)
+}
+
+func TestDecoder(t *testing.T) {
+	bs, err := os.ReadFile("test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Appendf(nil, string(bs), ">_<", "asd", 1, "mrow", "OWO")
+
+	var expected, out testStruct
+	if err := Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewDecoder(bytes.NewReader(data)).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.TestState = t
+	assert.Equals(out, expected)
+
+	type Item struct {
+		Name string `rjson:"name"`
+		Base string `rjson:"$.base"`
+	}
+
+	type record struct {
+		Id    int    `rjson:"id"`
+		Items []Item `rjson:"items"`
+	}
+
+	stream := `{"id": 1, "items": [{"name": "a"}], "base": "x", "skipped": {"deep": [[1], "]"]}}
+		{"base": "y", "items": [], "id": 2}
+		{"id": 3}`
+
+	dec := NewDecoder(strings.NewReader(stream))
+	var records []record
+	for {
+		var r record
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, r)
+	}
+
+	assert.Equals(records, []record{
+		{Id: 1, Items: []Item{{Name: "a", Base: "x"}}},
+		{Id: 2},
+		{Id: 3},
+	})
+}
@@ -0,0 +1,94 @@
+package rjson
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-json"
+)
+
+// maxSuggestions is how many keys a QueryError suggests at most
+const maxSuggestions = 3
+
+// objectKeys returns the keys of an object in document order, keys that are there more than once are only listed once
+func objectKeys(object json.RawMessage) []string {
+	members, err := objectMembers(object)
+	if err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(members))
+	for _, m := range members {
+		if !slices.Contains(keys, m.Key) {
+			keys = append(keys, m.Key)
+		}
+	}
+
+	return keys
+}
+
+// editDistance returns how many single rune edits turn a into b, swapping two neighbouring runes counts as one edit since its a common typo
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Only the last two rows of the matrix are needed
+	before := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range ra {
+		curr[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+
+			curr[j+1] = min(prev[j+1]+1, curr[j]+1, prev[j]+cost)
+			if i > 0 && j > 0 && ra[i] == rb[j-1] && ra[i-1] == rb[j] {
+				curr[j+1] = min(curr[j+1], before[j-1]+1)
+			}
+		}
+
+		before, prev, curr = prev, curr, before
+	}
+
+	return prev[len(rb)]
+}
+
+// suggestKeys returns the keys close enough to key to be a typo of it, closest first.
+// Case is ignored when measuring, so a key that only differs in case always comes first
+func suggestKeys(key string, keys []string) []string {
+	type candidate struct {
+		key      string
+		distance int // Ignoring case
+		exact    int // With case, breaks ties
+	}
+
+	// Short keys only allow one typo, otherwise every short key would be suggested
+	limit := max(1, utf8.RuneCountInString(key)/3)
+	lower := strings.ToLower(key)
+
+	var candidates []candidate
+	for _, k := range keys {
+		if d := editDistance(lower, strings.ToLower(k)); d <= limit {
+			candidates = append(candidates, candidate{key: k, distance: d, exact: editDistance(key, k)})
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.exact, b.exact))
+	})
+
+	suggestions := make([]string, 0, min(len(candidates), maxSuggestions))
+	for _, c := range candidates[:min(len(candidates), maxSuggestions)] {
+		suggestions = append(suggestions, c.key)
+	}
+
+	return suggestions
+}
@@ -0,0 +1,59 @@
+package rjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/BatteredBunny/testingassert"
+)
+
+func TestSuggestions(t *testing.T) {
+	data := []byte(`{"items": [{"thumbnails": [], "Title": "a", "title_id": 1, "views": 2, "id": 3}]}`)
+
+	cases := map[string][]string{
+		"items[0].thumbnail":  {"thumbnails"},
+		"items[0].title":      {"Title"},
+		"items[0].veiws":      {"views"},
+		"items[0].ID":         {"id"},
+		"items[0].completely": {},
+		"item":                {"items"},
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		_, err := QueryJson(data, tag)
+
+		var queryErr *QueryError
+		assert.Assert(errors.As(err, &queryErr), fmt.Sprintf("%s: expected a QueryError, got %v", tag, err))
+		assert.Equals(queryErr.Suggestions, expected, tag)
+	}
+
+	_, err := QueryJson(data, "items[0].thumbnail")
+	assert.Equals(err.Error(), `cant find field thumbnail at items[0] in "items[0].thumbnail"; did you mean "thumbnails"?`)
+
+	var queryErr *QueryError
+	errors.As(err, &queryErr)
+	assert.Equals(queryErr.Keys, []string{"thumbnails", "Title", "title_id", "views", "id"})
+
+	// Only missing keys get suggestions
+	_, err = QueryJson(data, "items[3]")
+	errors.As(err, &queryErr)
+	assert.Equals(len(queryErr.Keys), 0)
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := map[[2]string]int{
+		{"", ""}:                0,
+		{"abc", ""}:             3,
+		{"kitten", "sitting"}:   3,
+		{"thumbnail", "thumbs"}: 4,
+		{"ääb", "äb"}:           1,
+		{"veiws", "views"}:      1,
+	}
+
+	assert.TestState = t
+	for words, expected := range cases {
+		assert.Equals(editDistance(words[0], words[1]), expected, fmt.Sprint(words))
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/goccy/go-json"
 )
@@ -15,136 +17,397 @@ var ErrMalformedSyntax = errors.New("malformed tag syntax")
 var ErrCantFindField = errors.New("cant find field")
 var ErrInvalidIndex = errors.New("invalid slice index")
 var ErrNotAnObject = errors.New("failed to parse as json object")
+var ErrNotAnArray = errors.New("failed to parse as json array")
+var ErrInvalidJson = errors.New("invalid json")
 
 const TagName = "rjson"
 
-func iteratorExecutor(input []json.RawMessage, iteratorLiterals []string, iteratorLevel int, totalIteratorLevel int) (result []json.RawMessage) {
-	for _, row := range input {
-		if iteratorLevel > 1 {
-			var obj []json.RawMessage
-			if err := json.Unmarshal(row, &obj); err != nil {
-				log.Fatal(err)
-			}
+// isMissing reports if err means the path doesnt exist in the document, iterators skip such elements
+func isMissing(err error) bool {
+	return errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) || errors.Is(err, ErrNotAnObject)
+}
 
-			bs, err := json.Marshal(iteratorExecutor(obj, iteratorLiterals[1:], iteratorLevel-1, totalIteratorLevel))
-			if err != nil {
-				log.Fatal(err)
-			}
+// isUnresolved reports if err means the path doesnt fit the document, a missing value or a value of the wrong shape.
+// Alternatives move on to the next one for both, iterators still report arrays of the wrong shape they run into
+func isUnresolved(err error) bool {
+	return isMissing(err) || errors.Is(err, ErrNotAnArray)
+}
 
-			var res json.RawMessage
-			if err := json.Unmarshal(bs, &res); err != nil {
-				log.Fatal(err)
-			}
+// QueryError says where in the path a query failed and what it found there, errors.Is still matches it against the sentinel errors
+type QueryError struct {
+	Path     *Path  // The alternative that failed, the whole path when it has no alternatives
+	Segment  int    // Index of the failing segment in Path
+	Prefix   *Path  // Concrete path of the value the segment failed on, e.g items[3].tags for items[].tags[0]
+	Expected string // What the segment needed, object, array, or object or array for wildcards. Empty for segments that take any value
+	Found    string // Json type of the value the segment was applied to, e.g string
+	Err      error
+
+	Keys        []string // Keys of the object a missing key was looked up in, in document order
+	Suggestions []string // The keys closest to the missing one, best match first
+}
 
-			result = append(result, res)
-		} else {
-			var obj map[string]json.RawMessage
-			if err := json.Unmarshal(row, &obj); err != nil {
-				continue
-			}
+func (e *QueryError) Error() string {
+	msg := e.Err.Error()
+	if prefix := e.Prefix.String(); prefix != "" {
+		msg += " at " + prefix
+	}
+
+	msg = fmt.Sprintf("%s in %q", msg, e.Path)
+	if len(e.Suggestions) > 0 {
+		quoted := make([]string, len(e.Suggestions))
+		for i, key := range e.Suggestions {
+			quoted[i] = strconv.Quote(key)
+		}
+
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(quoted, " or "))
+	}
+
+	return msg
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// segmentError is the error of the segment at index, the whole path isnt known where it happens so the alternatives turn it into a *QueryError
+type segmentError struct {
+	index int
+	seg   Segment
+	path  []Segment       // Concrete path of the value the segment failed on, only known when the evaluator tracks paths
+	value json.RawMessage // What the segment was applied to, only looked at once the error is reported
+	err   error
+}
+
+func (e *segmentError) Error() string {
+	if path := (Alternative{Segments: e.path}).String(); path != "" {
+		return fmt.Sprintf("%s at %s", e.err, path)
+	}
+
+	return e.err.Error()
+}
+
+func (e *segmentError) Unwrap() error {
+	return e.err
+}
+
+// failSegment records that seg, the segment at index i, failed on m
+func failSegment(err error, i int, seg Segment, m match) error {
+	// Errors of paths nested in filter operands are reported as errors of the filter
+	if se, ok := err.(*segmentError); ok {
+		err = se.err
+	}
+
+	return &segmentError{index: i, seg: seg, path: slices.Clip(m.segments), value: m.value, err: err}
+}
+
+// shiftSegment moves an error of the segments after an iterator to its place in the whole path
+func shiftSegment(err error, offset int) error {
+	if se, ok := err.(*segmentError); ok {
+		se.index += offset
+	}
+
+	return err
+}
+
+// segmentExpects returns the json type a segment needs
+func segmentExpects(kind SegmentKind) string {
+	switch kind {
+	case KeySegment:
+		return "object"
+	case IndexSegment, LastSegment, IteratorSegment, SliceSegment, FilterSegment:
+		return "array"
+	case WildcardSegment:
+		return "object or array"
+	}
+
+	return ""
+}
 
-			var v json.RawMessage
-			if err := json.Unmarshal(obj[iteratorLiterals[len(iteratorLiterals)-1]], &v); err != nil {
+// queryError turns the error of a segment into a *QueryError, other errors are returned as is
+func queryError(alternative Alternative, err error) error {
+	se, ok := err.(*segmentError)
+	if !ok {
+		return err
+	}
+
+	queryErr := &QueryError{
+		Path:     &Path{Alternatives: []Alternative{alternative}},
+		Segment:  se.index,
+		Prefix:   &Path{Alternatives: []Alternative{{Segments: se.path}}},
+		Expected: segmentExpects(se.seg.Kind),
+		Found:    jsonKind(se.value),
+		Err:      se.err,
+	}
+
+	// The object the key is missing from is right there, its keys tell what the path should have been
+	if se.seg.Kind == KeySegment && errors.Is(se.err, ErrCantFindField) {
+		queryErr.Keys = objectKeys(se.value)
+		queryErr.Suggestions = suggestKeys(se.seg.Key, queryErr.Keys)
+	}
+
+	return queryErr
+}
+
+// evaluator holds the state shared by every step of a query
+type evaluator struct {
+	root  json.RawMessage // The whole document, referred to by $
+	index *index          // Shared between the queries of one Unmarshal, nil for single queries
+	track bool            // Keep the concrete path of every value and the values iterators put together
+}
+
+// newEvaluator checks that data is a single json value and returns an evaluator for it
+func newEvaluator(data []byte) (*evaluator, error) {
+	root := bytes.TrimSpace(data)
+	if len(root) == 0 {
+		return nil, fmt.Errorf("%w: empty document", ErrInvalidJson)
+	}
+
+	if err := validate(root); err != nil {
+		return nil, err
+	}
+
+	return &evaluator{root: root}, nil
+}
+
+// iterate resolves the remaining segments against every element,
+// elements that dont have the path are skipped or become null when aligned is set
+func (e *evaluator) iterate(m match, seg Segment, elements []match, segments []Segment) (match, error) {
+	// Only the values are needed unless paths are tracked
+	values := make([]json.RawMessage, 0, len(elements))
+	var results []match
+	dropped := 0
+	for _, element := range elements {
+		res, err := e.resolve(element, segments)
+		if isMissing(err) {
+			if !seg.Aligned {
+				dropped++
 				continue
 			}
 
-			result = append(result, v)
+			// The null stands where the value would have been
+			res = match{segments: e.concat(element.segments, segments), value: json.RawMessage("null")}
+		} else if err != nil {
+			return match{}, err
+		}
+
+		values = append(values, res.value)
+		if e.track {
+			results = append(results, res)
 		}
 	}
 
+	if Debug && dropped > 0 {
+		fmt.Printf("Iterator dropped %d of %d elements that dont have '%s'\n", dropped, len(elements), Alternative{Segments: segments})
+	}
+
+	return e.joinValues(m, seg, values, results), nil
+}
+
+// sliceIndexes returns the indexes python style slice bounds select from an array of length n
+func sliceIndexes(n int, r SliceRange) (indexes []int, err error) {
+	step := 1
+	if r.Step != nil {
+		step = *r.Step
+	}
+
+	if step == 0 {
+		err = fmt.Errorf("%w: slice step cant be zero", ErrMalformedSyntax)
+		return
+	}
+
+	// Bounds are clamped to lower..upper, for negative steps the slice can run down to before the first element
+	lower, upper := 0, n
+	start, end := lower, upper
+	if step < 0 {
+		lower, upper = -1, n-1
+		start, end = upper, lower
+	}
+
+	clamp := func(bound *int, fallback int) int {
+		if bound == nil {
+			return fallback
+		}
+
+		i := *bound
+		if i < 0 {
+			i += n
+		}
+
+		return max(lower, min(i, upper))
+	}
+
+	start = clamp(r.Start, start)
+	end = clamp(r.End, end)
+
+	indexes = []int{}
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		indexes = append(indexes, i)
+	}
+
 	return
 }
 
-// QueryJson is the underlying function powering the tag, accepts json as bytes
-func QueryJson(data []byte, tag string) (object json.RawMessage, err error) {
-	query, err := parse(tag)
+// sliceMatches applies python style slice bounds to matches
+func sliceMatches(matches []match, r SliceRange) ([]match, error) {
+	indexes, err := sliceIndexes(len(matches), r)
 	if err != nil {
-		err = fmt.Errorf("failed to parse tag '%s': %w", tag, err)
-		return
+		return nil, err
 	}
 
-	tokens := query.Tokens
+	result := make([]match, len(indexes))
+	for i, index := range indexes {
+		result[i] = matches[index]
+	}
 
-	if err = json.Unmarshal(data, &object); err != nil {
-		return
+	return result, nil
+}
+
+// executeSegments resolves the segments one by one starting from object
+func (e *evaluator) executeSegments(object json.RawMessage, segments []Segment) (json.RawMessage, error) {
+	m, err := e.resolve(match{value: object}, segments)
+	return m.value, err
+}
+
+// run resolves the segments starting from object. Paths arent kept track of while querying,
+// a query that fails is run again keeping track of them to find where it failed
+func (e *evaluator) run(object json.RawMessage, segments []Segment) (match, error) {
+	m, err := e.resolve(match{value: object}, segments)
+	if _, ok := err.(*segmentError); ok && !e.track {
+		tracked := *e
+		tracked.track = true
+		_, err = tracked.resolve(match{value: object}, segments)
 	}
 
-	var totalIteratorLevel int
-	for _, tok := range tokens {
-		if tok.Type == arrayIteratorToken {
-			totalIteratorLevel++
+	return m, err
+}
+
+// resolve resolves the segments one by one starting from m
+func (e *evaluator) resolve(m match, segments []Segment) (match, error) {
+	for i, seg := range segments {
+		if seg.Kind == PointerSegment {
+			var err error
+			if seg, err = e.resolvePointerSegment(m, seg.Key); err != nil {
+				return match{}, failSegment(err, i, seg, m)
+			}
 		}
-	}
 
-	var iteratorLevel int
-	var iteratorLiterals []string
-	for _, tok := range tokens {
-		switch tok.Type {
-		case literalToken:
-			if iteratorLevel > 0 {
-				iteratorLiterals = append(iteratorLiterals, tok.Content.(string))
+		switch seg.Kind {
+		case KeySegment:
+			v, err := e.lookupKey(m.value, seg.Key)
+			if err != nil {
+				return match{}, failSegment(err, i, seg, m)
+			}
 
-				var input []json.RawMessage
-				if err = json.Unmarshal(object, &input); err != nil {
-					return
-				}
+			m = e.step(m, seg, v)
+		case IndexSegment, LastSegment:
+			index := seg.Index
+			if seg.Kind == LastSegment {
+				index = -1
+			}
 
-				var bs []byte
-				bs, err = json.Marshal(iteratorExecutor(input, iteratorLiterals, iteratorLevel, totalIteratorLevel))
+			if m.joined {
+				at, err := elementIndex(index, len(m.elements))
 				if err != nil {
-					return
+					return match{}, failSegment(err, i, seg, m)
 				}
 
-				if err = json.Unmarshal(bs, &object); err != nil {
-					return
-				}
+				m = m.elements[at]
+				continue
+			}
+
+			v, at, err := e.arrayElement(m.value, index)
+			if err != nil {
+				return match{}, failSegment(err, i, seg, m)
+			}
+
+			m = e.step(m, indexStep(at), v)
+		case IteratorSegment, SliceSegment, WildcardSegment:
+			var elements []match
+			var err error
+			if seg.Kind == WildcardSegment {
+				elements, err = e.values(m)
 			} else {
-				var obj map[string]json.RawMessage
-				if err = json.Unmarshal(object, &obj); err != nil {
-					err = fmt.Errorf("%w %s: %s", ErrNotAnObject, tok.Content, err)
-					return
-				}
+				elements, err = e.elements(m)
+			}
+			if err != nil {
+				return match{}, failSegment(err, i, seg, m)
+			}
 
-				if err = json.Unmarshal(obj[tok.Content.(string)], &object); err != nil {
-					err = fmt.Errorf("%w %s: %s", ErrCantFindField, tok.Content, err)
-					return
+			if seg.Kind == SliceSegment {
+				if elements, err = sliceMatches(elements, seg.Slice); err != nil {
+					return match{}, failSegment(err, i, seg, m)
 				}
 			}
-		case arrayIndexToken:
-			var obj []json.RawMessage
-			if err = json.Unmarshal(object, &obj); err != nil {
-				return
+
+			res, err := e.iterate(m, seg, elements, segments[i+1:])
+			return res, shiftSegment(err, i+1)
+		case DescentSegment:
+			found, err := e.descendants(m, seg.Key)
+			if err != nil {
+				return match{}, failSegment(err, i, seg, m)
 			}
 
-			i := tok.Content.(int)
-			if i >= len(obj) {
-				err = fmt.Errorf("%w %d", ErrInvalidIndex, i)
-				return
-			} else {
-				object = obj[i]
+			m = e.join(m, seg, found)
+		case FilterSegment:
+			elements, err := e.elements(m)
+			if err != nil {
+				return match{}, failSegment(err, i, seg, m)
 			}
-		case arrayLastToken:
-			var obj []json.RawMessage
-			if err = json.Unmarshal(object, &obj); err != nil {
-				return
+
+			if seg.Filter == nil {
+				return match{}, failSegment(fmt.Errorf("%w: filter segment without an expression", ErrMalformedSyntax), i, seg, m)
 			}
 
-			i := len(obj) - 1
-			if i >= len(obj) {
-				err = fmt.Errorf("%w %d", ErrInvalidIndex, i)
-				return
-			} else {
-				object = obj[i]
+			filtered, err := e.filterMatches(elements, *seg.Filter)
+			if err != nil {
+				return match{}, failSegment(err, i, seg, m)
 			}
-		case arrayIteratorToken:
-			iteratorLevel++
+
+			m = e.join(m, seg, filtered)
+		case RootSegment:
+			m = match{value: e.root}
+		case CurrentSegment:
+			// The current element is what the path is already resolving against
 		}
 	}
 
-	return
+	return m, nil
 }
 
-func handleStructFields(data []byte, tag string, rv reflect.Value, notNested bool) (err error) {
+// QueryJson is the underlying function powering the tag, accepts json as bytes
+func QueryJson(data []byte, tag string) (object json.RawMessage, err error) {
+	path, err := Compile(tag)
+	if err != nil {
+		err = fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+		return
+	}
+
+	return path.Query(data)
+}
+
+// queryTag runs a struct tag against data, tags are only compiled once
+func queryTag(data []byte, e *evaluator, tag string) (json.RawMessage, error) {
+	path, err := compileTag(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag '%s': %w", tag, err)
+	}
+
+	return path.query(e, data)
+}
+
+// handleNestedStruct resolves the tag of a nested struct first so its fields are looked up relative to it, "." refers to the current object
+func handleNestedStruct(data []byte, e *evaluator, tag string, rv reflect.Value) (err error) {
+	if tag != "." {
+		if data, err = queryTag(data, e, tag); err != nil {
+			return
+		}
+	}
+
+	return handleStructFields(data, e, rv)
+}
+
+// handleStructFields fills the tagged fields of rv from data, which is part of the document e was made for
+func handleStructFields(data []byte, e *evaluator, rv reflect.Value) (err error) {
 	t := reflect.Indirect(rv).Type()
 	for i := range t.NumField() {
 		field := t.Field(i)
@@ -155,16 +418,9 @@ func handleStructFields(data []byte, tag string, rv reflect.Value, notNested boo
 			fmt.Printf("Handling field %s with tag name: %s, type: %v\n", field.Name, currentTag, field.Type.Kind())
 		}
 
-		var ct string
 		if currentTag != "" && field.IsExported() {
-			if tag == "" || tag == "." {
-				ct = currentTag
-			} else {
-				ct = fmt.Sprintf("%s.%s", tag, currentTag)
-			}
-
 			if field.Type.Kind() == reflect.Struct {
-				if err = handleStructFields(data, ct, valueField, false); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
+				if err = handleNestedStruct(data, e, currentTag, valueField); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
 					if Debug {
 						fmt.Println("WARNING:", err)
 					}
@@ -174,7 +430,7 @@ func handleStructFields(data []byte, tag string, rv reflect.Value, notNested boo
 					return
 				}
 			} else if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
-				if err = handleStructSlices(data, ct, valueField); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
+				if err = handleStructSlices(data, e, currentTag, valueField); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
 					if Debug {
 						fmt.Println("WARNING:", err)
 					}
@@ -184,7 +440,7 @@ func handleStructFields(data []byte, tag string, rv reflect.Value, notNested boo
 					return
 				}
 			} else {
-				if err = handleFields(data, ct, valueField); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
+				if err = handleFields(data, e, currentTag, valueField); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
 					if Debug {
 						fmt.Println("WARNING:", err)
 					}
@@ -202,15 +458,16 @@ func handleStructFields(data []byte, tag string, rv reflect.Value, notNested boo
 	return
 }
 
-func handleStructSlices(data []byte, tag string, rv reflect.Value) (err error) {
+func handleStructSlices(data []byte, e *evaluator, tag string, rv reflect.Value) (err error) {
 	var res json.RawMessage
-	res, err = QueryJson(data, tag)
+	res, err = queryTag(data, e, tag)
 	if err != nil {
 		return
 	}
 
+	// The elements are slices of the document, so their fields resolve against the shared index
 	var arr []json.RawMessage
-	if err = json.Unmarshal(res, &arr); err != nil {
+	if arr, err = e.decodeArray(res); err != nil {
 		return
 	}
 
@@ -218,13 +475,12 @@ func handleStructSlices(data []byte, tag string, rv reflect.Value) (err error) {
 		rv.Set(reflect.Append(rv, reflect.Indirect(reflect.New(rv.Type().Elem()))))
 		sv := rv.Index(j)
 
-		var bs []byte
-		bs, err = arr[j].MarshalJSON()
-		if err != nil {
-			return
+		// Null elements from aligned iterators are left as zero values
+		if jsonKind(arr[j]) == "null" {
+			continue
 		}
 
-		if err = handleStructFields(bs, "", sv, true); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
+		if err = handleStructFields(arr[j], e, sv); errors.Is(err, ErrCantFindField) || errors.Is(err, ErrInvalidIndex) {
 			if Debug {
 				fmt.Println("WARNING:", err)
 			}
@@ -248,12 +504,12 @@ func recursiveNumCheck(t reflect.Type) bool {
 	}
 }
 
-func handleFields(data []byte, tag string, rv reflect.Value) (err error) {
+func handleFields(data []byte, e *evaluator, tag string, rv reflect.Value) (err error) {
 	emptyValue := reflect.New(rv.Type())
 	inter := emptyValue.Interface()
 
 	var res json.RawMessage
-	res, err = QueryJson(data, tag)
+	res, err = queryTag(data, e, tag)
 	if err != nil {
 		return
 	}
@@ -281,7 +537,19 @@ func Unmarshal(data []byte, v any) (err error) {
 		return ErrNotAPointer
 	}
 
-	if err = handleStructFields(data, ".", rv, true); err != nil {
+	// Only the fields of a struct can have tags
+	if rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w to a struct, got %s", ErrNotAPointer, rv.Type())
+	}
+
+	// Every field is resolved against the same index so the document is only scanned once
+	e, err := newEvaluator(data)
+	if err != nil {
+		return err
+	}
+
+	e.index = newIndex()
+	if err = handleStructFields(data, e, rv); err != nil {
 		return
 	}
 
@@ -1,11 +1,14 @@
 package rjson
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
 
 	assert "github.com/BatteredBunny/testingassert"
+	"github.com/goccy/go-json"
 )
 
 type testStruct struct {
@@ -33,6 +36,12 @@ type testStruct struct {
 			Text string `rjson:"nya"`
 		} `rjson:"uwu"`
 	} `rjson:"."`
+	Fifteen   string `rjson:"jarray[1].\"1\""`
+	Sixteen   string `rjson:"headers[\"content-type\"]"`
+	Seventeen string `rjson:"headers.\"a.b\".c"`
+	Eighteen  string `rjson:"headers[\"quote\\\"d\"]"`
+	Nineteen  string `rjson:"combined[-2].str"`
+	Twenty    string `rjson:"one.arr[-1]"`
 }
 
 func TestTag(t *testing.T) {
@@ -71,4 +80,689 @@ func TestTag(t *testing.T) {
 	assert.Equals(out.Twelvev2, [][]string{{"1", "3"}, {"1", "4"}})
 	assert.Equals(out.Thirteen, []string{"Verified"})
 	assert.Equals(out.Fourteen.Eight.Text, one)
+	assert.Equals(out.Fifteen, "uwu")
+	assert.Equals(out.Sixteen, "application/json")
+	assert.Equals(out.Seventeen, "dotted")
+	assert.Equals(out.Eighteen, "escaped")
+	assert.Equals(out.Nineteen, "1")
+	assert.Equals(out.Twenty, three[1])
+}
+
+func TestInvalidIndex(t *testing.T) {
+	data := []byte(`{"arr": [1, 2, 3], "empty": []}`)
+
+	assert.TestState = t
+	for _, tag := range []string{"arr[3]", "arr[-4]", "empty[-]", "empty[0]", "empty[-1]"} {
+		_, err := QueryJson(data, tag)
+		assert.Assert(errors.Is(err, ErrInvalidIndex), fmt.Sprintf("%s: expected ErrInvalidIndex, got %v", tag, err))
+	}
+}
+
+func TestIterators(t *testing.T) {
+	data := []byte(`{
+		"arr": [1, 2, 3],
+		"matrix": [[1, 2], [3, 4], []],
+		"grid": [[{"a": 1}, {"a": 2}], [{"a": 3}]],
+		"rows": [[1, 2], [5], []],
+		"objs": [{"a": [1, 2]}, {"b": 1}, {"a": []}]
+	}`)
+
+	cases := map[string]string{
+		"arr[]":                `[1,2,3]`,
+		"arr[].x":              `[]`,
+		"matrix[][0]":          `[1,3]`,
+		"matrix[][1]":          `[2,4]`,
+		"matrix[][-]":          `[2,4]`,
+		"matrix[][-1]":         `[2,4]`,
+		"matrix[][]":           `[[1,2],[3,4],[]]`,
+		"matrix[][1:]":         `[[2],[4],[]]`,
+		"matrix[][?(@ > 1)]":   `[[2],[3,4],[]]`,
+		"matrix[][0] | length": `2`,
+		"rows[][-]":            `[2,5]`,
+		"rows[][0]":            `[1,5]`,
+		"grid[][]":             `[[{"a":1},{"a":2}],[{"a":3}]]`,
+		"grid[][].a":           `[[1,2],[3]]`,
+		"grid[][0].a":          `[1,3]`,
+		"grid[]..a":            `[[1,2],[3]]`,
+		"objs[].a":             `[[1,2],[]]`,
+		"objs[].a[0]":          `[1]`,
+		"objs[].a[]":           `[[1,2],[]]`,
+		"objs[].*":             `[[[1,2]],[1],[[]]]`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	_, err := QueryJson(data, "arr[0][0]")
+	assert.Assert(errors.Is(err, ErrNotAnArray), fmt.Sprintf("expected ErrNotAnArray, got %v", err))
+
+	var out struct {
+		Firsts []int   `rjson:"matrix[][0]"`
+		Lasts  []int   `rjson:"rows[][-]"`
+		Matrix [][]int `rjson:"matrix[][]"`
+		Grid   [][]int `rjson:"grid[][].a"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Firsts, []int{1, 3})
+	assert.Equals(out.Lasts, []int{2, 5})
+	assert.Equals(out.Matrix, [][]int{{1, 2}, {3, 4}, {}})
+	assert.Equals(out.Grid, [][]int{{1, 2}, {3}})
+}
+
+func TestAlignedIterators(t *testing.T) {
+	data := []byte(`{
+		"a": [
+			{"name": "x", "id": 1},
+			{"name": "y"},
+			{"id": 3},
+			"text"
+		],
+		"obj": {"one": {"id": 1}, "two": {}},
+		"nested": [{"v": {"id": 1}}, {}, {"v": {"id": 3}}]
+	}`)
+
+	cases := map[string]string{
+		"a[]?.name":     `["x","y",null,null]`,
+		"a[]?.id":       `[1,null,3,null]`,
+		"a[1:]?.id":     `[null,3,null]`,
+		"obj.*?.id":     `[1,null]`,
+		"obj[*]?.id":    `[1,null]`,
+		"a[]?.id.x":     `[null,null,null,null]`,
+		"a[].id":        `[1,3]`,
+		"a[]? | length": `4`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	type Entry struct {
+		Id int `rjson:"id"`
+	}
+
+	var out struct {
+		Names   []string `rjson:"a[]?.name"`
+		Ids     []int    `rjson:"a[]?.id"`
+		Entries []Entry  `rjson:"nested[]?.v"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Names, []string{"x", "y", "", ""})
+	assert.Equals(out.Ids, []int{1, 0, 3, 0})
+	assert.Equals(out.Entries, []Entry{{1}, {0}, {3}})
+}
+
+func TestSlice(t *testing.T) {
+	data := []byte(`{"numbers": [0, 1, 2, 3, 4, 5], "rows": [{"text": "a"}, {"text": "b"}, {"none": "c"}, {"text": "d"}]}`)
+
+	cases := map[string]string{
+		"numbers[1:4]":     "[1,2,3]",
+		"numbers[:2]":      "[0,1]",
+		"numbers[4:]":      "[4,5]",
+		"numbers[-2:]":     "[4,5]",
+		"numbers[:-4]":     "[0,1]",
+		"numbers[::2]":     "[0,2,4]",
+		"numbers[1::2]":    "[1,3,5]",
+		"numbers[::-1]":    "[5,4,3,2,1,0]",
+		"numbers[-2::-2]":  "[4,2,0]",
+		"numbers[10:]":     "[]",
+		"numbers[3:1]":     "[]",
+		"rows[1:].text":    `["b","d"]`,
+		"rows[::2].text":   `["a"]`,
+		"numbers[-100:2]":  "[0,1]",
+		"numbers[:100:-1]": "[]",
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	_, err := QueryJson(data, "numbers[::0]")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), "zero slice step should be rejected")
+
+	var out struct {
+		Texts []string `rjson:"rows[1:].text"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Texts, []string{"b", "d"})
+}
+
+func TestFilter(t *testing.T) {
+	data := []byte(`{
+		"formats": [
+			{"mime": "audio/mp4", "bitrate": 128, "url": "a"},
+			{"mime": "video/mp4", "bitrate": 1000, "url": "b", "hdr": true, "tags": ["hd"]},
+			{"mime": "video/webm", "bitrate": 900.5, "url": "c", "hdr": false, "tags": "hd"},
+			{"mime": "video/mp4", "bitrate": 2500, "url": "d"}
+		],
+		"numbers": [1, 5, 10, -3]
+	}`)
+
+	cases := map[string]string{
+		`formats[?(@.mime == "video/mp4")][0].url`:                     `"b"`,
+		`formats[?(@.mime == "video/mp4")][-].url`:                     `"d"`,
+		`formats[?(@.mime == "video/mp4")][].url`:                      `["b","d"]`,
+		`formats[?(@.mime != "video/mp4")][].url`:                      `["a","c"]`,
+		`formats[?(@.bitrate > 900.5)][].url`:                          `["b","d"]`,
+		`formats[?(@.bitrate >= 900.5)][].url`:                         `["b","c","d"]`,
+		`formats[?(@.bitrate < 1000 && @.mime != "audio/mp4")][].url`:  `["c"]`,
+		`formats[?(@.mime == "audio/mp4" || @.bitrate <= 1000)][].url`: `["a","b","c"]`,
+		`formats[?(@.hdr)][].url`:                                      `["b","c"]`,
+		`formats[?(!@.hdr)][].url`:                                     `["a","d"]`,
+		`formats[?(@.hdr == true)][].url`:                              `["b"]`,
+		`formats[?(!(@.hdr == false) && @.mime > "b")][].url`:          `["b","d"]`,
+		`formats[?(@.missing == null)]`:                                `[]`,
+		`numbers[?(@ > 1)]`:                                            `[5,10]`,
+		`numbers[?(@ == -3)]`:                                          `[-3]`,
+		`formats[?(@.tags[0] == "hd")][].url`:                          `["b"]`,
+		`formats[?(@.tags[0])][].url`:                                  `["b"]`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	var out struct {
+		Url string `rjson:"formats[?(@.mime == \"video/mp4\")][0].url"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Url, "b")
+}
+
+func TestWildcard(t *testing.T) {
+	data := []byte(`{
+		"users": {
+			"u2": {"name": "bob", "age": 30},
+			"u1": {"name": "alice", "age": 25},
+			"u3": {"age": 40}
+		},
+		"arr": [{"name": "x"}, {"name": "y"}]
+	}`)
+
+	cases := map[string]string{
+		"users.*.name": `["bob","alice"]`,
+		"users[*].age": `[30,25,40]`,
+		"arr.*.name":   `["x","y"]`,
+		"*.u1.name":    `["alice"]`,
+		"users.u3.*":   `[40]`,
+		"users.*.*":    `[["bob",30],["alice",25],[40]]`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	var out struct {
+		Names []string `rjson:"users.*.name"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Names, []string{"bob", "alice"})
+}
+
+func TestDescent(t *testing.T) {
+	data := []byte(`{
+		"contents": {
+			"tabs": [
+				{"section": {"items": [
+					{"playlistVideoRenderer": {"title": {"runs": [{"text": "first"}, {"text": "ignored"}]}}},
+					{"other": {"playlistVideoRenderer": {"title": {"runs": [{"text": "second"}]}}}}
+				]}},
+				{"playlistVideoRenderer": {"title": {"runs": [{"text": "third"}]}}}
+			]
+		},
+		"a": {"id": 1, "b": {"id": 2}, "c": [{"id": 3}]},
+		"id": 4
+	}`)
+
+	cases := map[string]string{
+		"..id":       `[1,2,3,4]`,
+		"a..id":      `[1,2,3]`,
+		"a.c..id":    `[3]`,
+		"..missing":  `[]`,
+		"..id[-]":    `4`,
+		"a.b..id[0]": `2`,
+		"..playlistVideoRenderer[].title.runs[0].text": `["first","second","third"]`,
+		`contents.tabs[].."text"`:                      `[["first","ignored","second"],["third"]]`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	var out struct {
+		Titles []string `rjson:"..playlistVideoRenderer[].title.runs[0].text"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Titles, []string{"first", "second", "third"})
+}
+
+func TestAlternatives(t *testing.T) {
+	data := []byte(`{
+		"video": {"title": "a", "lengthText": {"simpleText": "1:00"}},
+		"arr": [1, 2],
+		"str": "text"
+	}`)
+
+	cases := map[string]string{
+		"video.title | video.headline":               `"a"`,
+		"video.headline | video.title":               `"a"`,
+		"video.length | video.lengthText.simpleText": `"1:00"`,
+		"arr[5] | arr[-3] | arr[0]":                  `1`,
+		"str.nested | str":                           `"text"`,
+		"zz | str[0] | str":                          `"text"`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	_, err := QueryJson(data, "video.headline | video.subtitle")
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+
+	var out struct {
+		Title  string `rjson:"video.headline | video.title"`
+		Length struct {
+			Text string `rjson:"simpleText | accessibility.label"`
+		} `rjson:"video.length | video.lengthText"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Title, "a")
+	assert.Equals(out.Length.Text, "1:00")
+
+	// Elements of another shape fall back like missing ones instead of failing the whole Unmarshal
+	var items struct {
+		Items []struct {
+			Tag string `rjson:"tags[0] | tags"`
+		} `rjson:"items"`
+	}
+
+	if err := Unmarshal([]byte(`{"items": [{"tags": ["x", "y"]}, {"tags": "str"}]}`), &items); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(items.Items[0].Tag, "x")
+	assert.Equals(items.Items[1].Tag, "str")
+}
+
+func TestRootReferences(t *testing.T) {
+	data := []byte(`{
+		"config": {"baseUrl": "https://example.com", "minAge": 18},
+		"items": [
+			{"path": "/a", "age": 20},
+			{"path": "/b", "age": 10}
+		]
+	}`)
+
+	cases := map[string]string{
+		"$":                string(data),
+		"$.config.baseUrl": `"https://example.com"`,
+		"@.config.minAge":  `18`,
+		"$..baseUrl":       `["https://example.com"]`,
+		"items[?(@.age >= $.config.minAge)][].path": `["/a"]`,
+		"items[?(@.age < $.config.minAge)][].path":  `["/b"]`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	type Item struct {
+		Path string `rjson:"@.path"`
+		Base string `rjson:"$.config.baseUrl"`
+	}
+
+	var out struct {
+		Items  []Item `rjson:"items"`
+		Config struct {
+			MinAge int    `rjson:"minAge"`
+			First  string `rjson:"$.items[0].path"`
+		} `rjson:"config"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(len(out.Items), 2)
+	assert.Equals(out.Items[1].Path, "/b")
+	assert.Equals(out.Items[1].Base, "https://example.com")
+	assert.Equals(out.Config.MinAge, 18)
+	assert.Equals(out.Config.First, "/a")
+}
+
+func TestFunctions(t *testing.T) {
+	data := []byte(`{
+		"items": [{"id": 1, "name": "A"}, {"id": 2, "name": "B"}, {"id": 3}],
+		"first": {"name": "A"},
+		"keys": [{"id": 1}, {"id": 2}],
+		"obj": {"b": 1, "a": "x"},
+		"tags": ["go", 1, true, null],
+		"text": "  Hello, World  ",
+		"csv": "a,b,c",
+		"num": "42.5",
+		"big": "12345678901234567890",
+		"empty": [],
+		"length": 7
+	}`)
+
+	cases := map[string]string{
+		"items | length":              `3`,
+		"obj | length":                `2`,
+		"csv | length":                `5`,
+		"obj | keys":                  `["b","a"]`,
+		"items | keys":                `[0,1,2]`,
+		"obj | values":                `[1,"x"]`,
+		"items | first":               `{"id": 1, "name": "A"}`,
+		"items | last | keys":         `["id"]`,
+		"items[].name | join(\", \")": `"A, B"`,
+		`tags | join("-")`:            `"go-1-true-"`,
+		`csv | split(",")`:            `["a","b","c"]`,
+		`csv | split(",") | length`:   `3`,
+		"text | trim | lower":         `"hello, world"`,
+		"text | trim | upper":         `"HELLO, WORLD"`,
+		"items[0] | tostring":         `"{\"id\":1,\"name\":\"A\"}"`,
+		"csv | tostring":              `"a,b,c"`,
+		"num | tonumber":              `42.5`,
+		"items[0].id | tonumber":      `1`,
+		"big | tonumber":              `12345678901234567890`,
+		`missing | "length"`:          `7`,
+		"missing | obj | length":      `2`,
+		"items | obj | length":        `3`,
+		"missing | first.name":        `"A"`,
+		"missing | items[0].name":     `"A"`,
+		"missing | keys[1].id":        `2`,
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		res, err := QueryJson(data, tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tag, err)
+		}
+
+		assert.Equals(string(res), expected, fmt.Sprintf("%s: '%s' is not '%s'", tag, res, expected))
+	}
+
+	for _, tag := range []string{"items | upper", "obj | first", "text | tonumber"} {
+		_, err := QueryJson(data, tag)
+		assert.Assert(errors.Is(err, ErrInvalidFunctionInput), fmt.Sprintf("%s: expected ErrInvalidFunctionInput, got %v", tag, err))
+	}
+
+	_, err := QueryJson(data, "items | join")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	// Functions go after the last alternative and apply to whichever one resolves
+	_, err = Compile("items | length | obj")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	var out struct {
+		Count int      `rjson:"items | other | length"`
+		Keys  []string `rjson:"obj | keys"`
+		Names string   `rjson:"items[].name | join(\",\")"`
+	}
+
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equals(out.Count, 3)
+	assert.Equals(out.Keys, []string{"b", "a"})
+	assert.Equals(out.Names, "A,B")
+}
+
+func TestParseError(t *testing.T) {
+	assert.TestState = t
+
+	_, err := QueryJson([]byte(`{}`), "items[?]")
+	assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("expected ErrMalformedSyntax, got %v", err))
+
+	var parseErr *ParseError
+	assert.Assert(errors.As(err, &parseErr), fmt.Sprintf("expected a ParseError, got %v", err))
+	assert.Equals(parseErr.Path, "items[?]")
+	assert.Equals(parseErr.Offset, 7)
+	assert.Equals(parseErr.Token, "]")
+	assert.Equals(parseErr.Expected, []string{"("})
+	assert.Equals(parseErr.Caret(), "       ^")
+
+	_, err = parse(`headers["content-type`)
+	assert.Assert(errors.As(err, &parseErr), fmt.Sprintf("expected a ParseError, got %v", err))
+	assert.Equals(parseErr.Offset, 8)
+	assert.Equals(parseErr.Token, `"content-type`)
+
+	_, err = parse("a.")
+	assert.Assert(errors.As(err, &parseErr), fmt.Sprintf("expected a ParseError, got %v", err))
+	assert.Equals(parseErr.Offset, 2)
+	assert.Equals(parseErr.Token, "")
+}
+
+func TestTypeErrors(t *testing.T) {
+	data := []byte(`{"items": [{"tags": ["c"]}, {"tags": "a,b"}], "obj": {"a": [1], "b": "x"}, "name": "x"}`)
+
+	cases := map[string]string{
+		"items[1].tags[0]":  "failed to parse as json array, found string at items[1].tags in \"items[1].tags[0]\"",
+		"items[].tags[0]":   "failed to parse as json array, found string at items[1].tags in \"items[].tags[0]\"",
+		"items[-].tags[]":   "failed to parse as json array, found string at items[1].tags in \"items[-].tags[]\"",
+		"items[1:].tags[0]": "failed to parse as json array, found string at items[1].tags in \"items[1:].tags[0]\"",
+		"obj.*[0]":          "failed to parse as json array, found string at obj.b in \"obj.*[0]\"",
+		"name.given":        "failed to parse as json object, found string at name in \"name.given\"",
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		_, err := QueryJson(data, tag)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tag)
+		}
+
+		assert.Equals(err.Error(), expected, tag)
+	}
+
+	_, err := QueryJson([]byte(`["x", "y"]`), "[][0]")
+	assert.Assert(errors.Is(err, ErrNotAnArray), fmt.Sprintf("expected ErrNotAnArray, got %v", err))
+	assert.Equals(err.Error(), `failed to parse as json array, found string at [0] in "[][0]"`)
+
+	var out struct {
+		Tags []string `rjson:"items[].tags[0]"`
+	}
+	err = Unmarshal(data, &out)
+	assert.Assert(errors.Is(err, ErrNotAnArray), fmt.Sprintf("expected ErrNotAnArray, got %v", err))
+
+	// Elements missing a key are still left out
+	res, err := QueryJson(data, "items[].missing")
+	assert.Equals(err, nil)
+	assert.Equals(string(res), `[]`)
+}
+
+func TestQueryError(t *testing.T) {
+	data := []byte(`{"items": [{"tags": "a,b"}, {"tags": ["c"]}], "name": "x", "one": {"two": {}}}`)
+
+	cases := map[string]QueryError{
+		"items[0].tags[0]":   {Segment: 3, Prefix: MustCompile("items[0].tags"), Expected: "array", Found: "string"},
+		"items[].tags[0]":    {Segment: 3, Prefix: MustCompile("items[0].tags"), Expected: "array", Found: "string"},
+		"items[-2].tags[]":   {Segment: 3, Prefix: MustCompile("items[0].tags"), Expected: "array", Found: "string"},
+		"name.first":         {Segment: 1, Prefix: MustCompile("name"), Expected: "object", Found: "string"},
+		"name.*":             {Segment: 1, Prefix: MustCompile("name"), Expected: "object or array", Found: "string"},
+		"items.first":        {Segment: 1, Prefix: MustCompile("items"), Expected: "object", Found: "array"},
+		"items[5]":           {Segment: 1, Prefix: MustCompile("items"), Expected: "array", Found: "array"},
+		"one.two.three":      {Segment: 2, Prefix: MustCompile("one.two"), Expected: "object", Found: "object"},
+		"missing | one.nope": {Segment: 1, Prefix: MustCompile("one"), Expected: "object", Found: "object"},
+	}
+
+	assert.TestState = t
+	for tag, expected := range cases {
+		_, err := QueryJson(data, tag)
+
+		var queryErr *QueryError
+		assert.Assert(errors.As(err, &queryErr), fmt.Sprintf("%s: expected a QueryError, got %v", tag, err))
+		assert.Equals(queryErr.Segment, expected.Segment, tag)
+		assert.Equals(queryErr.Prefix.String(), expected.Prefix.String(), tag)
+		assert.Equals(queryErr.Expected, expected.Expected, tag)
+		assert.Equals(queryErr.Found, expected.Found, tag)
+	}
+
+	_, err := QueryJson(data, "one.two.three")
+	assert.Assert(errors.Is(err, ErrCantFindField), fmt.Sprintf("expected ErrCantFindField, got %v", err))
+	assert.Equals(err.Error(), `cant find field three at one.two in "one.two.three"`)
+
+	_, err = QueryJson(data, "items[5]")
+	assert.Assert(errors.Is(err, ErrInvalidIndex), fmt.Sprintf("expected ErrInvalidIndex, got %v", err))
+	assert.Equals(err.Error(), `invalid slice index 5, array has 2 elements at items in "items[5]"`)
+
+	_, err = QueryJson([]byte(`[1]`), "a")
+	assert.Equals(err.Error(), `failed to parse as json object, found array in "a"`)
+
+	// Errors after iterators keep their place in the whole path
+	_, err = QueryJson([]byte(`{"a": [{"b": [1]}, {"b": "x"}]}`), "a[].b[1]")
+	var queryErr *QueryError
+	assert.Assert(errors.As(err, &queryErr), fmt.Sprintf("expected a QueryError, got %v", err))
+	assert.Equals(queryErr.Segment, 3)
+	assert.Equals(queryErr.Path.String(), "a[].b[1]")
+	assert.Equals(queryErr.Prefix.String(), "a[1].b")
+
+	_, err = QueryAll(data, "one.two.three")
+	assert.Assert(errors.As(err, &queryErr), fmt.Sprintf("expected a QueryError, got %v", err))
+	assert.Equals(queryErr.Segment, 2)
+}
+
+func TestNoPanics(t *testing.T) {
+	assert.TestState = t
+
+	var i int
+	err := Unmarshal([]byte(`{}`), &i)
+	assert.Assert(errors.Is(err, ErrNotAPointer), fmt.Sprintf("expected ErrNotAPointer, got %v", err))
+
+	// Paths built by hand can be missing what the parser would require
+	paths := []*Path{
+		{Alternatives: []Alternative{{Segments: []Segment{{Kind: FilterSegment}}}}},
+		{Alternatives: []Alternative{{Segments: []Segment{{Kind: FilterSegment, Filter: &FilterExpr{Op: FilterNot}}}}}},
+		{Alternatives: []Alternative{{Segments: []Segment{{Kind: FilterSegment, Filter: &FilterExpr{Op: FilterEq}}}}}},
+		{Alternatives: []Alternative{{}}, Functions: []Function{{Name: "join"}}},
+	}
+
+	for _, path := range paths {
+		_ = path.String()
+
+		_, err := path.Query([]byte(`[1, 2]`))
+		assert.Assert(errors.Is(err, ErrMalformedSyntax), fmt.Sprintf("%s: expected ErrMalformedSyntax, got %v", path, err))
+	}
+}
+
+// FuzzQueryJson makes sure no document or path can make the library panic
+func FuzzQueryJson(f *testing.F) {
+	seeds, err := os.ReadFile("test.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, tag := range []string{"one.arr[0]", "combined[].str", "nesteditter[]?.thing[1:].a", "..test", `badges[?(@.metadata.value == "x")]`, "$.uwu.* | keys", "/jarray/0"} {
+		f.Add(seeds, tag)
+	}
+	f.Add([]byte(`[1, [2, "3"], {"a": null}]`), "[][0]")
+	f.Add([]byte(`{"a": [1 2}`), "a[-]")
+
+	f.Fuzz(func(t *testing.T, data []byte, tag string) {
+		QueryJson(data, tag)
+		QueryAll(data, tag)
+		Get(data, tag).Map()
+		QueryReader(bytes.NewReader(data), tag)
+
+		// Edits have to leave valid json behind
+		if res, err := Set(data, tag, 1, CreateMissing()); err == nil && !json.Valid(res) {
+			t.Errorf("%q: Set gave invalid json %q", tag, res)
+		}
+		if res, err := Delete(data, tag); err == nil && !json.Valid(res) {
+			t.Errorf("%q: Delete gave invalid json %q", tag, res)
+		}
+
+		if path, err := Compile(tag); err == nil {
+			if _, err := Compile(path.String()); err != nil {
+				t.Errorf("%q: canonical form %q doesnt parse: %s", tag, path, err)
+			}
+		}
+
+		var out struct {
+			Value any      `rjson:"a"`
+			Items []string `rjson:"[]"`
+			Rows  []struct {
+				Name string `rjson:"name"`
+			} `rjson:"rows[]?"`
+		}
+		Unmarshal(data, &out)
+	})
 }